@@ -0,0 +1,228 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// DropPolicy decides what an AsyncObserver does with a new event when its
+// queue is full.
+type DropPolicy int
+
+const (
+	// DropNewest discards the new event
+	DropNewest DropPolicy = iota
+	// DropOldest discards the event at the head of the queue to make room
+	DropOldest
+	// Block waits for room in the queue, which adds the wait to the evaluation
+	Block
+)
+
+// AsyncObserver hands evaluations off to another Observer on a pool of worker
+// goroutines, so that a slow Observer does not add to the latency of the flag
+// evaluation itself. A panic in that Observer is handed to the PanicHandler of
+// the ObservedEvaluator which made the evaluation (or logged, if it has none).
+type AsyncObserver struct {
+	// the counters lead the struct, as 64-bit atomics must be 64-bit aligned on
+	// 32-bit platforms, and only the first word of an allocated struct is sure
+	// to be. (The same goes for the counters of the other types here.)
+	queued  uint64
+	dropped uint64
+
+	next   Observer
+	policy DropPolicy
+	queue  chan asyncEvent
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	// mu guards sending on queue against closing it
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+type asyncEvent struct {
+	ctx             context.Context
+	key             string
+	user            lduser.User
+	callsiteDefault ldvalue.Value
+	elapsed         time.Duration
+	detail          ldreason.EvaluationDetail
+	evalErr         error
+}
+
+// NewAsyncObserver starts the given number of workers, which drain a queue of
+// the given size into next. It is the caller's responsibility to Close it.
+func NewAsyncObserver(next Observer, size, workers int, policy DropPolicy) (*AsyncObserver, error) {
+	if next == nil {
+		return nil, fmt.Errorf("observer must not be nil")
+	}
+	if size < 1 {
+		return nil, fmt.Errorf("size must be positive")
+	}
+	if workers < 1 {
+		return nil, fmt.Errorf("workers must be positive")
+	}
+	ao := &AsyncObserver{
+		next:   next,
+		policy: policy,
+		queue:  make(chan asyncEvent, size),
+		stop:   make(chan struct{}),
+	}
+	ao.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go ao.work()
+	}
+	return ao, nil
+}
+
+// Observe conforms to the Observer interface. The context handed on to the
+// next Observer keeps the values of ctx, but not its cancellation.
+func (ao *AsyncObserver) Observe(
+	ctx context.Context,
+	key string,
+	user lduser.User,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	ev := asyncEvent{
+		ctx:             detached{ctx},
+		key:             key,
+		user:            user,
+		callsiteDefault: callsiteDefault,
+		elapsed:         elapsed,
+		detail:          detail,
+		evalErr:         evalErr,
+	}
+
+	ao.mu.RLock()
+	defer ao.mu.RUnlock()
+	if ao.closed {
+		atomic.AddUint64(&ao.dropped, 1)
+		return
+	}
+
+	switch ao.policy {
+	case Block:
+		select {
+		case ao.queue <- ev:
+			atomic.AddUint64(&ao.queued, 1)
+		case <-ao.stop:
+			atomic.AddUint64(&ao.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case ao.queue <- ev:
+				atomic.AddUint64(&ao.queued, 1)
+				return
+			default:
+			}
+			select {
+			case <-ao.queue:
+				atomic.AddUint64(&ao.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case ao.queue <- ev:
+			atomic.AddUint64(&ao.queued, 1)
+		default:
+			atomic.AddUint64(&ao.dropped, 1)
+		}
+	}
+}
+
+// Queued is the number of events that have been accepted onto the queue
+func (ao *AsyncObserver) Queued() uint64 {
+	return atomic.LoadUint64(&ao.queued)
+}
+
+// Dropped is the number of events that have been discarded, either by the
+// DropPolicy, or because they arrived after Close
+func (ao *AsyncObserver) Dropped() uint64 {
+	return atomic.LoadUint64(&ao.dropped)
+}
+
+// Pending is the number of events currently waiting on the queue
+func (ao *AsyncObserver) Pending() int {
+	return len(ao.queue)
+}
+
+// Close stops accepting new events, and waits for the workers to drain the
+// queue. If ctx is done first, Close returns its error, and the workers carry
+// on draining in the background.
+func (ao *AsyncObserver) Close(ctx context.Context) error {
+	ao.closeOnce.Do(func() {
+		// release any Observe blocked on a full queue, so we can get the lock
+		close(ao.stop)
+		ao.mu.Lock()
+		ao.closed = true
+		close(ao.queue)
+		ao.mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ao.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (ao *AsyncObserver) work() {
+	defer ao.wg.Done()
+	for ev := range ao.queue {
+		ao.deliver(ev)
+	}
+}
+
+func (ao *AsyncObserver) deliver(ev asyncEvent) {
+	// there is no evaluation here to protect, but a panic on a worker
+	// goroutine would still take down the whole process
+	defer func() {
+		if r := recover(); r != nil {
+			handlePanic(panicHandlerFrom(ev.ctx), &ObserverPanic{
+				Observer: ao.next,
+				Index:    -1,
+				Key:      ev.key,
+				Value:    r,
+				Stack:    debug.Stack(),
+			})
+		}
+	}()
+	ao.next.Observe(ev.ctx, ev.key, ev.user, ev.callsiteDefault, ev.elapsed, ev.detail, ev.evalErr)
+}
+
+// detached keeps the values of its Context, but not its deadline or cancellation
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
@@ -0,0 +1,263 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+	"gopkg.in/launchdarkly/go-server-sdk.v5/interfaces/flagstate"
+)
+
+// Client is the interface that describes the methods on an LDClient beyond
+// those of the Evaluator, which an ObservedClient also observes.
+type Client interface {
+	Evaluator
+	AllFlagsState(user lduser.User, options ...flagstate.Option) flagstate.AllFlags
+	Identify(user lduser.User) error
+	TrackEvent(eventName string, user lduser.User) error
+	TrackData(eventName string, user lduser.User, data ldvalue.Value) error
+	TrackMetric(eventName string, user lduser.User, metricValue float64, data ldvalue.Value) error
+}
+
+// ClientObserver is interested in the operations of a Client other than the
+// evaluation of individual flags. Any of its callbacks may be nil.
+type ClientObserver struct {
+	// OnAllFlagsState is invoked once per call to AllFlagsState, with the
+	// state returned, after the evaluation of each flag in it has been observed
+	OnAllFlagsState func(ctx context.Context, user lduser.User, state flagstate.AllFlags, elapsed time.Duration)
+
+	// OnIdentify is invoked for every call to Identify
+	OnIdentify func(ctx context.Context, user lduser.User, err error)
+
+	// OnTrackEvent is invoked for every call to TrackEvent
+	OnTrackEvent func(ctx context.Context, eventName string, user lduser.User, err error)
+
+	// OnTrackData is invoked for every call to TrackData
+	OnTrackData func(ctx context.Context, eventName string, user lduser.User, data ldvalue.Value, err error)
+
+	// OnTrackMetric is invoked for every call to TrackMetric
+	OnTrackMetric func(ctx context.Context, eventName string, user lduser.User, metricValue float64, data ldvalue.Value, err error)
+}
+
+// ObservedClient is a drop-in replacement for an LDClient, which observes
+// every method of the Client interface. Evaluations of individual flags are
+// handled by its ObservedEvaluator, as are the flags included in each
+// AllFlagsState, so that flags bootstrapped to browsers show up in the same
+// telemetry (and are subject to the same Interceptors and Registry) as the
+// rest. Its ClientObservers share the PanicHandler and quarantine of the
+// ObservedEvaluator.
+type ObservedClient struct {
+	*ObservedEvaluator
+	client            Client
+	observers         []ClientObserver
+	clientQuarantines []*quarantine
+}
+
+// NewObservedClient wraps the ObservedEvaluator (which must itself wrap a
+// Client) with the given observers of the other Client methods.
+func NewObservedClient(oe *ObservedEvaluator, observers ...ClientObserver) (*ObservedClient, error) {
+	if oe == nil {
+		return nil, fmt.Errorf("evaluator must not be nil")
+	}
+	client, ok := oe.client.(Client)
+	if !ok {
+		return nil, fmt.Errorf("evaluator must wrap a Client; got %T", oe.client)
+	}
+	return &ObservedClient{
+		ObservedEvaluator: oe,
+		client:            client,
+		observers:         observers,
+	}, nil
+}
+
+func (oc *ObservedClient) WithContext(c context.Context) Evaluator {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithContext(c).(*ObservedEvaluator)
+	return &cp
+}
+
+// WithInterceptors is as per ObservedEvaluator.WithInterceptors, but returns
+// a copy of the ObservedClient
+func (oc *ObservedClient) WithInterceptors(interceptors ...Interceptor) (*ObservedClient, error) {
+	oe, err := oc.ObservedEvaluator.WithInterceptors(interceptors...)
+	if err != nil {
+		return nil, err
+	}
+	cp := *oc
+	cp.ObservedEvaluator = oe
+	return &cp, nil
+}
+
+// WithPanicHandler is as per ObservedEvaluator.WithPanicHandler, but returns
+// a copy of the ObservedClient, whose ClientObservers report to fn too
+func (oc *ObservedClient) WithPanicHandler(fn PanicHandler) *ObservedClient {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithPanicHandler(fn)
+	return &cp
+}
+
+// WithQuarantine is as per ObservedEvaluator.WithQuarantine, but returns a
+// copy of the ObservedClient, whose ClientObservers are quarantined too
+func (oc *ObservedClient) WithQuarantine(limit int, window, cooldown time.Duration) *ObservedClient {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithQuarantine(limit, window, cooldown)
+	cp.clientQuarantines = newQuarantines(len(oc.observers), limit, window, cooldown)
+	return &cp
+}
+
+// WithRegistry is as per ObservedEvaluator.WithRegistry, but returns a copy
+// of the ObservedClient
+func (oc *ObservedClient) WithRegistry(r *Registry) *ObservedClient {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithRegistry(r)
+	return &cp
+}
+
+// AllFlagsState conforms to the Client interface. Every flag in the returned
+// state is then run through the same chain as an evaluation of AllFlagsKind,
+// with a null callsite default, and the state standing in for the client
+// beneath the Interceptors; as the flags are not known beforehand, this all
+// happens after the call. Any flag whose result an Interceptor changes (as
+// Override would) is changed in the state returned, too, unless it is changed
+// to null (as the callsite default of KillSwitch would be).
+func (oc *ObservedClient) AllFlagsState(user lduser.User, options ...flagstate.Option) flagstate.AllFlags {
+	start := time.Now()
+	state := oc.client.AllFlagsState(user, options...)
+	elapsed := time.Since(start)
+
+	if changed := oc.observeFlags(user, state); len(changed) > 0 {
+		builder := flagstate.NewAllFlagsBuilder(options...)
+		for key := range state.ToValuesMap() {
+			fs, _ := state.GetFlag(key)
+			if detail, ok := changed[key]; ok {
+				fs.Value, fs.Variation, fs.Reason = detail.Value, detail.VariationIndex, detail.Reason
+			}
+			builder.AddFlag(key, fs)
+		}
+		state = builder.Build()
+	}
+
+	oc.notify("AllFlagsState", func(co ClientObserver) {
+		if co.OnAllFlagsState != nil {
+			co.OnAllFlagsState(oc.ctx, user, state, elapsed)
+		}
+	})
+	return state
+}
+
+// observeFlags runs each flag in the state through the ObservedEvaluator, in
+// order of key, and returns the details of those whose result was changed
+func (oc *ObservedClient) observeFlags(user lduser.User, state flagstate.AllFlags) map[string]ldreason.EvaluationDetail {
+	values := state.ToValuesMap()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var changed map[string]ldreason.EvaluationDetail
+	for _, key := range keys {
+		fs, _ := state.GetFlag(key)
+		served := ldreason.EvaluationDetail{Value: fs.Value, VariationIndex: fs.Variation, Reason: fs.Reason}
+		req := EvaluationRequest{
+			Key:             key,
+			User:            user,
+			CallsiteDefault: ldvalue.Null(),
+			Kind:            AllFlagsKind,
+		}
+		detail, _ := oc.evaluateWith(req, func(context.Context, EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			return served, nil
+		})
+		// an Interceptor serving the callsite default (as KillSwitch does)
+		// can only serve null here, which would turn a flag that is on into
+		// null in the browser; the flag is left as it was served instead
+		if !sameDetail(detail, served) && !detail.Value.IsNull() {
+			if changed == nil {
+				changed = map[string]ldreason.EvaluationDetail{}
+			}
+			changed[key] = detail
+		}
+	}
+	return changed
+}
+
+// Identify conforms to the Client interface
+func (oc *ObservedClient) Identify(user lduser.User) error {
+	err := oc.client.Identify(user)
+	oc.notify("Identify", func(co ClientObserver) {
+		if co.OnIdentify != nil {
+			co.OnIdentify(oc.ctx, user, err)
+		}
+	})
+	return err
+}
+
+// TrackEvent conforms to the Client interface
+func (oc *ObservedClient) TrackEvent(eventName string, user lduser.User) error {
+	err := oc.client.TrackEvent(eventName, user)
+	oc.notify(eventName, func(co ClientObserver) {
+		if co.OnTrackEvent != nil {
+			co.OnTrackEvent(oc.ctx, eventName, user, err)
+		}
+	})
+	return err
+}
+
+// TrackData conforms to the Client interface
+func (oc *ObservedClient) TrackData(eventName string, user lduser.User, data ldvalue.Value) error {
+	err := oc.client.TrackData(eventName, user, data)
+	oc.notify(eventName, func(co ClientObserver) {
+		if co.OnTrackData != nil {
+			co.OnTrackData(oc.ctx, eventName, user, data, err)
+		}
+	})
+	return err
+}
+
+// TrackMetric conforms to the Client interface
+func (oc *ObservedClient) TrackMetric(eventName string, user lduser.User, metricValue float64, data ldvalue.Value) error {
+	err := oc.client.TrackMetric(eventName, user, metricValue, data)
+	oc.notify(eventName, func(co ClientObserver) {
+		if co.OnTrackMetric != nil {
+			co.OnTrackMetric(oc.ctx, eventName, user, metricValue, data, err)
+		}
+	})
+	return err
+}
+
+// notify hands each observer to fn, recovering any panic so that it doesn't
+// take down the operation (or the observers after it)
+func (oc *ObservedClient) notify(key string, fn func(co ClientObserver)) {
+	for i, co := range oc.observers {
+		if oc.clientQuarantines != nil && oc.clientQuarantines[i].active(time.Now()) {
+			continue
+		}
+		func() {
+			defer oc.recoverClient(i, key)
+			fn(co)
+		}()
+	}
+}
+
+func (oc *ObservedClient) recoverClient(i int, key string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	p := &ObserverPanic{
+		Observer: oc.observers[i],
+		Index:    i,
+		Key:      key,
+		Value:    r,
+		Stack:    debug.Stack(),
+	}
+	if oc.clientQuarantines != nil {
+		p.Quarantined = oc.clientQuarantines[i].record(time.Now())
+	}
+	handlePanic(oc.onPanic, p)
+}
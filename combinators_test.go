@@ -0,0 +1,218 @@
+package goldhook_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+	ld "gopkg.in/launchdarkly/go-server-sdk.v5"
+
+	"github.com/nelz9999/goldhook"
+)
+
+func keyRecorder(keys *[]string) goldhook.Observer {
+	return goldhook.ObserverFunc(
+		func(_ context.Context, key string, _ lduser.User, _ ldvalue.Value, _ time.Duration, _ ldreason.EvaluationDetail, _ error) {
+			*keys = append(*keys, key)
+		},
+	)
+}
+
+func TestCombinators(t *testing.T) {
+	glob, err := goldhook.KeyGlob("glob-*")
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, err := goldhook.KeyGlob("["); err == nil {
+		t.Errorf("expected error for bad pattern\n")
+	}
+	re, err := goldhook.KeyRegexp(regexp.MustCompile(`^regexp-\d+$`))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUser("combinators")
+	fallthrough_ := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthrough())
+	notFound := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorFlagNotFound, ldvalue.Bool(false))
+	wrongType := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorWrongType, ldvalue.Bool(false))
+	failure := errors.New("failure")
+
+	inputs := []struct {
+		key    string
+		detail ldreason.EvaluationDetail
+		err    error
+	}{
+		{"exact", fallthrough_, nil},
+		{"prefix-one", fallthrough_, nil},
+		{"glob-one", notFound, failure},
+		{"regexp-123", wrongType, nil},
+	}
+
+	testCases := []struct {
+		name     string
+		wrap     func(goldhook.Observer) (goldhook.Observer, error)
+		expected []string
+	}{
+		{
+			"exact",
+			func(o goldhook.Observer) (goldhook.Observer, error) {
+				return goldhook.FilterKeys(o, goldhook.ExactKeys("exact", "missing"))
+			},
+			[]string{"exact"},
+		},
+		{
+			"prefix",
+			func(o goldhook.Observer) (goldhook.Observer, error) {
+				return goldhook.FilterKeys(o, goldhook.KeyPrefix("prefix-"))
+			},
+			[]string{"prefix-one"},
+		},
+		{
+			"glob",
+			func(o goldhook.Observer) (goldhook.Observer, error) {
+				return goldhook.FilterKeys(o, glob)
+			},
+			[]string{"glob-one"},
+		},
+		{
+			"regexp",
+			func(o goldhook.Observer) (goldhook.Observer, error) {
+				return goldhook.FilterKeys(o, re)
+			},
+			[]string{"regexp-123"},
+		},
+		{
+			"reasons",
+			func(o goldhook.Observer) (goldhook.Observer, error) {
+				return goldhook.FilterReasons(o, ldreason.EvalReasonFallthrough)
+			},
+			[]string{"exact", "prefix-one"},
+		},
+		{
+			"error kinds",
+			func(o goldhook.Observer) (goldhook.Observer, error) {
+				return goldhook.FilterErrorKinds(o, ldreason.EvalErrorWrongType)
+			},
+			[]string{"regexp-123"},
+		},
+		{
+			"only errors",
+			goldhook.OnlyErrors,
+			[]string{"glob-one"},
+		},
+		{
+			"composed",
+			func(o goldhook.Observer) (goldhook.Observer, error) {
+				errs, err := goldhook.FilterReasons(o, ldreason.EvalReasonError)
+				if err != nil {
+					return nil, err
+				}
+				return goldhook.FilterKeys(errs, goldhook.KeyPrefix("prefix-"))
+			},
+			[]string{},
+		},
+	}
+	for _, tc := range testCases {
+		keys := []string{}
+		observer, err := tc.wrap(keyRecorder(&keys))
+		if err != nil {
+			t.Fatalf("%s: unexpected: %v\n", tc.name, err)
+		}
+		for _, in := range inputs {
+			observer.Observe(context.Background(), in.key, user, ldvalue.Bool(false), 0, in.detail, in.err)
+		}
+		if fmt.Sprint(keys) != fmt.Sprint(tc.expected) {
+			t.Errorf("%s: expected %v; got %v\n", tc.name, tc.expected, keys)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	keys := []string{}
+	sampled, err := goldhook.Sample(keyRecorder(&keys), 0.25)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	for i := 0; i < 1000; i++ {
+		user := lduser.NewUser(fmt.Sprintf("user-%d", i))
+		// the same context is always sampled the same way
+		sampled.Observe(context.Background(), "first", user, ldvalue.Null(), 0, ldreason.EvaluationDetail{}, nil)
+		sampled.Observe(context.Background(), "second", user, ldvalue.Null(), 0, ldreason.EvaluationDetail{}, nil)
+	}
+	if len(keys) < 400 || len(keys) > 600 {
+		t.Errorf("sample - expected roughly %d; got %d\n", 500, len(keys))
+	}
+	for i := 0; i < len(keys); i += 2 {
+		if keys[i] != "first" || keys[i+1] != "second" {
+			t.Fatalf("sample - expected pairs; got %v\n", keys[i:i+2])
+		}
+	}
+
+	for _, rate := range []float64{0, 1} {
+		keys = []string{}
+		sampled, err = goldhook.Sample(keyRecorder(&keys), rate)
+		if err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+		for i := 0; i < 10; i++ {
+			sampled.Observe(context.Background(), "key", lduser.NewUser(fmt.Sprint(i)), ldvalue.Null(), 0, ldreason.EvaluationDetail{}, nil)
+		}
+		if len(keys) != int(rate*10) {
+			t.Errorf("rate %v - expected %d; got %d\n", rate, int(rate*10), len(keys))
+		}
+	}
+}
+
+func TestMulti(t *testing.T) {
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	first, second := []string{}, []string{}
+	filtered, err := goldhook.FilterKeys(keyRecorder(&second), goldhook.KeyPrefix("b"))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	multi, err := goldhook.Multi(keyRecorder(&first), filtered)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err := goldhook.NewEvaluator(context.Background(), client, multi)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUser("multi")
+	hooked.BoolVariation("a", user, false)
+	hooked.BoolVariation("b", user, false)
+
+	if fmt.Sprint(first) != "[a b]" || fmt.Sprint(second) != "[b]" {
+		t.Errorf("multi - expected [a b] and [b]; got %v and %v\n", first, second)
+	}
+}
+
+func TestCombinatorsNil(t *testing.T) {
+	keys := []string{}
+	if _, err := goldhook.FilterKeys(nil, goldhook.KeyPrefix("a")); err == nil {
+		t.Errorf("expected error for nil observer\n")
+	}
+	if _, err := goldhook.FilterKeys(keyRecorder(&keys), nil); err == nil {
+		t.Errorf("expected error for nil key matcher\n")
+	}
+	if _, err := goldhook.KeyRegexp(nil); err == nil {
+		t.Errorf("expected error for nil regexp\n")
+	}
+	if _, err := goldhook.OnlyErrors(nil); err == nil {
+		t.Errorf("expected error for nil observer\n")
+	}
+	if _, err := goldhook.Multi(keyRecorder(&keys), nil); err == nil {
+		t.Errorf("expected error for nil observer\n")
+	}
+}
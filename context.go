@@ -0,0 +1,37 @@
+package goldhook
+
+import (
+	"context"
+	"net/http"
+)
+
+type evaluatorKey struct{}
+
+// NewContext returns a copy of ctx which carries the Evaluator, so that code
+// further down the call chain can evaluate flags without it being passed along.
+func NewContext(ctx context.Context, e Evaluator) context.Context {
+	return context.WithValue(ctx, evaluatorKey{}, e)
+}
+
+// FromContext returns the Evaluator carried by ctx, if any. If it is a
+// ContextualEvaluator, it is bound to ctx itself (as per WithContext), so that
+// Observers are handed ctx, along with anything added to it since NewContext.
+func FromContext(ctx context.Context) (Evaluator, bool) {
+	e, ok := ctx.Value(evaluatorKey{}).(Evaluator)
+	if ce, contextual := e.(ContextualEvaluator); contextual {
+		return ce.WithContext(ctx), true
+	}
+	return e, ok
+}
+
+// Middleware puts the ContextualEvaluator into each request's context, for
+// handlers to retrieve via FromContext. Observers are then handed the context
+// that it is retrieved from, without every callsite having to remember to
+// call WithContext.
+func Middleware(ce ContextualEvaluator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), ce)))
+		})
+	}
+}
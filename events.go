@@ -0,0 +1,152 @@
+package goldhook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// EventVersion is the version of EvaluationEvent this package produces. It is
+// bumped whenever fields are added, so that consumers can tell what to expect.
+const EventVersion = 1
+
+// EvaluationEvent carries everything the ObservedEvaluator knows about a single
+// flag evaluation. Fields will be added over time, so it should not be
+// constructed or compared positionally.
+type EvaluationEvent struct {
+	Version int
+
+	Key             string
+	User            lduser.User
+	CallsiteDefault ldvalue.Value
+	Kind            ValueKind
+
+	Start   time.Time
+	Elapsed time.Duration
+	Detail  ldreason.EvaluationDetail
+	Err     error
+
+	// InterceptedBy names the Interceptor which changed the result, if any
+	InterceptedBy string
+
+	// Extensions holds whatever was attached to the evaluation via SetExtension
+	Extensions map[string]interface{}
+}
+
+// NewEvaluationEvent assembles an EvaluationEvent from its parts
+func NewEvaluationEvent(req EvaluationRequest, result EvaluationResult) EvaluationEvent {
+	return EvaluationEvent{
+		Version:         EventVersion,
+		Key:             req.Key,
+		User:            req.User,
+		CallsiteDefault: req.CallsiteDefault,
+		Kind:            req.Kind,
+		Start:           result.Start,
+		Elapsed:         result.Elapsed,
+		Detail:          result.Detail,
+		Err:             result.Err,
+		InterceptedBy:   result.InterceptedBy,
+		Extensions:      result.Extensions,
+	}
+}
+
+// EventObserver is interested in knowing the details about the results of
+// a feature flag evaluation, as an EvaluationEvent.
+type EventObserver interface {
+	ObserveEvent(ctx context.Context, ev EvaluationEvent)
+}
+
+// EventObserverFunc is a function adapter for the EventObserver interface
+type EventObserverFunc func(ctx context.Context, ev EvaluationEvent)
+
+// ObserveEvent conforms to the EventObserver interface
+func (fn EventObserverFunc) ObserveEvent(ctx context.Context, ev EvaluationEvent) {
+	fn(ctx, ev)
+}
+
+// ObserverEvents adapts an Observer into an EventObserver
+func ObserverEvents(o Observer) EventObserver {
+	return EventObserverFunc(func(ctx context.Context, ev EvaluationEvent) {
+		ctx = withInterceptedBy(ctx, ev.InterceptedBy)
+		o.Observe(ctx, ev.Key, ev.User, ev.CallsiteDefault, ev.Elapsed, ev.Detail, ev.Err)
+	})
+}
+
+// StagedEvents adapts an EventObserver into a StagedObserver, which does
+// nothing before the evaluation and invokes ObserveEvent after it.
+func StagedEvents(eo EventObserver) StagedObserver {
+	return eventStages{eo}
+}
+
+type eventStages struct {
+	EventObserver
+}
+
+func (es eventStages) BeforeEvaluation(context.Context, EvaluationRequest) SeriesData {
+	return nil
+}
+
+func (es eventStages) AfterEvaluation(ctx context.Context, req EvaluationRequest, _ SeriesData, result EvaluationResult) {
+	es.ObserveEvent(ctx, NewEvaluationEvent(req, result))
+}
+
+type extensionsKey struct{}
+
+type extensions struct {
+	mu sync.Mutex
+	m  map[string]interface{}
+}
+
+func withExtensions(ctx context.Context) (context.Context, *extensions) {
+	ext := &extensions{}
+	return context.WithValue(ctx, extensionsKey{}, ext), ext
+}
+
+func (ext *extensions) snapshot() map[string]interface{} {
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	if len(ext.m) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(ext.m))
+	for k, v := range ext.m {
+		m[k] = v
+	}
+	return m
+}
+
+// SetExtension attaches a value to the evaluation in progress, which observers
+// then receive in EvaluationEvent.Extensions (or via Extension). It may be
+// called by a StagedObserver's BeforeEvaluation, an Interceptor, or the
+// underlying client, using the context they were handed; it reports false if
+// that context does not belong to an evaluation.
+func SetExtension(ctx context.Context, key string, value interface{}) bool {
+	ext, ok := ctx.Value(extensionsKey{}).(*extensions)
+	if !ok {
+		return false
+	}
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	if ext.m == nil {
+		ext.m = map[string]interface{}{}
+	}
+	ext.m[key] = value
+	return true
+}
+
+// Extension reports the value attached to the evaluation in progress via
+// SetExtension, if any.
+func Extension(ctx context.Context, key string) (interface{}, bool) {
+	ext, ok := ctx.Value(extensionsKey{}).(*extensions)
+	if !ok {
+		return nil, false
+	}
+	ext.mu.Lock()
+	defer ext.mu.Unlock()
+	value, ok := ext.m[key]
+	return value, ok
+}
@@ -0,0 +1,124 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+	ld "gopkg.in/launchdarkly/go-server-sdk.v5"
+
+	"github.com/nelz9999/goldhook"
+)
+
+// idStage attaches an evaluation ID before each evaluation
+type idStage struct {
+	next int
+}
+
+func (is *idStage) BeforeEvaluation(ctx context.Context, _ goldhook.EvaluationRequest) goldhook.SeriesData {
+	is.next++
+	goldhook.SetExtension(ctx, "id", is.next)
+	return nil
+}
+
+func (is *idStage) AfterEvaluation(context.Context, goldhook.EvaluationRequest, goldhook.SeriesData, goldhook.EvaluationResult) {
+}
+
+func TestEventObserver(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	events := []goldhook.EvaluationEvent{}
+	recorder := goldhook.EventObserverFunc(func(_ context.Context, ev goldhook.EvaluationEvent) {
+		events = append(events, ev)
+	})
+
+	// old-style observers can still see extensions, through the context
+	sources := []interface{}{}
+	legacy := goldhook.ObserverFunc(
+		func(ctx context.Context, _ string, _ lduser.User, _ ldvalue.Value, _ time.Duration, _ ldreason.EvaluationDetail, _ error) {
+			source, _ := goldhook.Extension(ctx, "source")
+			sources = append(sources, source)
+		},
+	)
+
+	hooked, err := goldhook.NewStagedEvaluator(
+		context.Background(),
+		client,
+		&idStage{},
+		goldhook.StagedEvents(recorder),
+		goldhook.StagedEvents(goldhook.ObserverEvents(legacy)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(
+		goldhook.NewInterceptor("source", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+			return func(ctx context.Context, req goldhook.EvaluationRequest) (ldreason.EvaluationDetail, error) {
+				goldhook.SetExtension(ctx, "source", now)
+				return next(ctx, req)
+			}
+		}),
+		goldhook.Override("forced", ldvalue.Int(7)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewAnonymousUser(now)
+	before := time.Now()
+	hooked.StringVariation("plain", user, now)
+	hooked.IntVariation("forced", user, 42)
+
+	if len(events) != 2 {
+		t.Fatalf("events - expected %d; got %d\n", 2, len(events))
+	}
+	for i, ev := range events {
+		if ev.Version != goldhook.EventVersion {
+			t.Errorf("version - expected %d; got %d\n", goldhook.EventVersion, ev.Version)
+		}
+		if ev.User.GetKey() != now {
+			t.Errorf("context - expected %q; got %q\n", now, ev.User.GetKey())
+		}
+		if ev.Start.Before(before) {
+			t.Errorf("start - expected after %v; got %v\n", before, ev.Start)
+		}
+		if ev.Extensions["id"] != i+1 || ev.Extensions["source"] != now {
+			t.Errorf("extensions - expected id %d from %q; got %v\n", i+1, now, ev.Extensions)
+		}
+		if sources[i] != now {
+			t.Errorf("legacy - expected %q; got %v\n", now, sources[i])
+		}
+	}
+
+	plain, forced := events[0], events[1]
+	if plain.Key != "plain" || plain.Kind != goldhook.StringKind || plain.InterceptedBy != "" {
+		t.Errorf("plain - unexpected: %+v\n", plain)
+	}
+	if !plain.CallsiteDefault.Equal(ldvalue.String(now)) {
+		t.Errorf("plain - expected %q; got %v\n", now, plain.CallsiteDefault)
+	}
+	if forced.Key != "forced" || forced.Kind != goldhook.IntKind || forced.InterceptedBy != "override:forced" {
+		t.Errorf("forced - unexpected: %+v\n", forced)
+	}
+	if forced.Detail.Value.IntValue() != 7 {
+		t.Errorf("forced - expected %d; got %v\n", 7, forced.Detail.Value)
+	}
+}
+
+func TestExtensionOutsideEvaluation(t *testing.T) {
+	if goldhook.SetExtension(context.Background(), "key", "value") {
+		t.Errorf("expected no evaluation in progress\n")
+	}
+	if _, ok := goldhook.Extension(context.Background(), "key"); ok {
+		t.Errorf("expected no evaluation in progress\n")
+	}
+}
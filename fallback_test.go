@@ -0,0 +1,168 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+	ld "gopkg.in/launchdarkly/go-server-sdk.v5"
+
+	"github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/goldhooktest"
+)
+
+func TestFallbackEvaluator(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	if _, err := goldhook.NewFallbackEvaluator(context.Background()); err == nil {
+		t.Errorf("expected an error for no sources\n")
+	}
+	if _, err := goldhook.NewFallbackEvaluator(context.Background(), goldhook.FlagSource{Name: "empty"}); err == nil {
+		t.Errorf("expected an error for a source without Evaluate\n")
+	}
+
+	// an offline client is never ready
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer client.Close()
+
+	snapshot, err := goldhook.OpenSnapshot(filepath.Join(t.TempDir(), "flags.json"), 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer snapshot.Close()
+	snapshot.Record("theme", ldvalue.String("dark"))
+
+	fallback, err := goldhook.NewFallbackEvaluator(context.Background(),
+		goldhook.ClientSource("launchdarkly", client),
+		snapshot.Source("snapshot"),
+		goldhook.StaticSource("static", map[string]ldvalue.Value{
+			"theme": ldvalue.String("light"),
+			"limit": ldvalue.Int(10),
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var _ goldhook.ContextualEvaluator = fallback
+
+	servedBy := map[string]interface{}{}
+	observer := goldhook.EventObserverFunc(func(_ context.Context, ev goldhook.EvaluationEvent) {
+		servedBy[ev.Key] = ev.Extensions[goldhook.ServedByExtension]
+	})
+	hooked, err := goldhook.NewEventEvaluator(context.Background(), fallback, observer)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUser(now)
+	if v, err := hooked.StringVariation("theme", user, "none"); err != nil || v != "dark" {
+		t.Errorf("theme - unexpected: %v %v\n", v, err)
+	}
+	if v, err := hooked.IntVariation("limit", user, 0); err != nil || v != 10 {
+		t.Errorf("limit - unexpected: %v %v\n", v, err)
+	}
+	// the last source's result is served when every source falls through
+	_, detail, _ := hooked.BoolVariationDetail("missing", user, true)
+	if !detail.Value.BoolValue() || detail.Reason.GetErrorKind() != ldreason.EvalErrorFlagNotFound {
+		t.Errorf("missing - unexpected: %+v\n", detail)
+	}
+	// as is a value of the wrong type
+	_, detail, _ = hooked.BoolVariationDetail("theme", user, false)
+	if detail.Reason.GetErrorKind() != ldreason.EvalErrorWrongType {
+		t.Errorf("wrong type - unexpected: %+v\n", detail)
+	}
+
+	expected := map[string]interface{}{
+		"theme":   "static",
+		"limit":   "static",
+		"missing": "static",
+	}
+	// theme was last served by the static source, given the wrong type
+	if fmt.Sprint(servedBy) != fmt.Sprint(expected) {
+		t.Errorf("expected %v; got %v\n", expected, servedBy)
+	}
+	hooked.StringVariation("theme", user, "none")
+	if servedBy["theme"] != "snapshot" {
+		t.Errorf("expected %q; got %v\n", "snapshot", servedBy["theme"])
+	}
+
+	// specific reasons fall through too
+	offIsFallThrough := fallback.WithFallThrough(ldreason.EvalReasonOff)
+	if v, _ := offIsFallThrough.StringVariation("theme", user, "none"); v != "light" {
+		t.Errorf("expected %q; got %q\n", "light", v)
+	}
+}
+
+func TestFallbackEvaluatorSnapshot(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	primary := goldhooktest.NewFake().
+		Set("theme", goldhooktest.Serve(ldvalue.String(now), 1)).
+		Set("broken", goldhooktest.Fail(fmt.Errorf("broken"))).
+		Set("off", goldhooktest.Serve(ldvalue.Bool(true), 0).WithReason(ldreason.NewEvalReasonOff())).
+		Set("unindexed", goldhooktest.Result{Value: ldvalue.Bool(true)}).
+		Set("banner", goldhooktest.Serve(ldvalue.String("everyone"), 0)).
+		SetFor("banner", "other-"+now, goldhooktest.Serve(ldvalue.String("other"), 1))
+	snapshot, err := goldhook.OpenSnapshot(filepath.Join(t.TempDir(), "flags.json"), 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer snapshot.Close()
+	snapshot.Record("broken", ldvalue.String("fixed"))
+
+	fallback, err := goldhook.NewFallbackEvaluator(context.Background(),
+		goldhook.ClientSource("primary", primary),
+		snapshot.Source("snapshot"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	fallback = fallback.WithSnapshot(snapshot)
+
+	user := lduser.NewUser(now)
+	if v, err := fallback.StringVariation("theme", user, ""); err != nil || v != now {
+		t.Errorf("unexpected: %v %v\n", v, err)
+	}
+	if v, err := fallback.StringVariation("broken", user, ""); err != nil || v != "fixed" {
+		t.Errorf("unexpected: %v %v\n", v, err)
+	}
+
+	// only values from the first source are recorded
+	if v, ok := snapshot.Lookup("theme"); !ok || v.StringValue() != now {
+		t.Errorf("unexpected: %v %t\n", v, ok)
+	}
+	if v, _ := snapshot.Lookup("broken"); v.StringValue() != "fixed" {
+		t.Errorf("unexpected: %v\n", v)
+	}
+
+	// and only variations of flags which are on
+	fallback.BoolVariation("off", user, false)
+	fallback.BoolVariation("unindexed", user, false)
+	for _, key := range []string{"off", "unindexed"} {
+		if v, ok := snapshot.Lookup(key); ok {
+			t.Errorf("%s - expected no value; got %v\n", key, v)
+		}
+	}
+
+	// a flag which serves users differently isn't recorded
+	if v, _ := fallback.StringVariation("banner", user, ""); v != "everyone" {
+		t.Errorf("unexpected: %v\n", v)
+	}
+	if v, ok := snapshot.Lookup("banner"); !ok || v.StringValue() != "everyone" {
+		t.Errorf("unexpected: %v %t\n", v, ok)
+	}
+	if v, _ := fallback.StringVariation("banner", lduser.NewUser("other-"+now), ""); v != "other" {
+		t.Errorf("unexpected: %v\n", v)
+	}
+	if v, ok := snapshot.Lookup("banner"); ok {
+		t.Errorf("expected no value; got %v\n", v)
+	}
+}
@@ -0,0 +1,101 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/goldhooktest"
+)
+
+type checkoutConfig struct {
+	Steps   []string `json:"steps"`
+	Timeout int      `json:"timeout"`
+}
+
+var (
+	checkoutV2    = goldhook.BoolFlag("checkout-v2", false, "the new checkout flow")
+	checkoutRatio = goldhook.Float64Flag("checkout-ratio", 0.5, "share of traffic to the new checkout")
+	checkoutLimit = goldhook.IntFlag("checkout-limit", 10, "most items in a single checkout")
+	checkoutTheme = goldhook.StringFlag("checkout-theme", "light", "look of the checkout pages")
+	checkoutSteps = goldhook.JSONFlag("checkout-config", checkoutConfig{Steps: []string{"pay"}}, "checkout configuration")
+)
+
+func TestFlag(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := goldhooktest.NewFake().
+		Set("checkout-v2", goldhooktest.Serve(ldvalue.Bool(true), 1)).
+		Set("checkout-ratio", goldhooktest.Serve(ldvalue.Float64(0.25), 1)).
+		Set("checkout-limit", goldhooktest.Serve(ldvalue.Int(3), 2)).
+		Set("checkout-theme", goldhooktest.Serve(ldvalue.String(now), 0)).
+		Set("checkout-config", goldhooktest.Serve(ldvalue.Parse([]byte(`{"steps":["cart","pay"],"timeout":30}`)), 0))
+
+	seen := map[string]goldhook.FlagMetadata{}
+	observer := goldhook.ObserverFunc(
+		func(ctx context.Context, key string, _ lduser.User, _ ldvalue.Value, _ time.Duration, _ ldreason.EvaluationDetail, _ error) {
+			if fm, ok := goldhook.FlagFromContext(ctx); ok {
+				seen[key] = fm
+			}
+		},
+	)
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake, observer)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUser(now)
+	if _, err := checkoutV2.Get(context.Background(), user); err == nil {
+		t.Errorf("expected an error without an evaluator\n")
+	}
+
+	ctx := goldhook.NewContext(context.Background(), hooked)
+	if v, err := checkoutV2.Get(ctx, user); err != nil || !v {
+		t.Errorf("bool - unexpected: %v %v\n", v, err)
+	}
+	if v, err := checkoutRatio.Get(ctx, user); err != nil || v != 0.25 {
+		t.Errorf("float64 - unexpected: %v %v\n", v, err)
+	}
+	if v, err := checkoutLimit.Get(ctx, user); err != nil || v != 3 {
+		t.Errorf("int - unexpected: %v %v\n", v, err)
+	}
+	if v, err := checkoutTheme.Get(ctx, user); err != nil || v != now {
+		t.Errorf("string - unexpected: %v %v\n", v, err)
+	}
+	if v, err := checkoutSteps.Get(ctx, user); err != nil || fmt.Sprint(v) != "{[cart pay] 30}" {
+		t.Errorf("json - unexpected: %v %v\n", v, err)
+	}
+
+	fm, ok := seen["checkout-config"]
+	if !ok || fm.Kind != goldhook.JSONKind || fm.Description != "checkout configuration" {
+		t.Errorf("metadata - unexpected: %+v\n", fm)
+	}
+	if !fm.Default.Equal(ldvalue.Parse([]byte(`{"steps":["pay"],"timeout":0}`))) {
+		t.Errorf("metadata - unexpected default: %s\n", fm.Default.JSONString())
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected metadata for %d flags; got %v\n", 5, seen)
+	}
+
+	// values which don't decode get the default
+	fake.Set("checkout-config", goldhooktest.Serve(ldvalue.String(now), 1))
+	v, detail, err := checkoutSteps.Evaluate(context.Background(), hooked, user)
+	if err == nil || fmt.Sprint(v) != "{[pay] 0}" || detail.Value.StringValue() != now {
+		t.Errorf("undecodable - unexpected: %v %+v %v\n", v, detail, err)
+	}
+
+	// plain Evaluators work too
+	if v, _, err := checkoutLimit.Evaluate(context.Background(), fake, user); err != nil || v != 3 {
+		t.Errorf("plain - unexpected: %v %v\n", v, err)
+	}
+	var undeclared goldhook.Flag[bool]
+	if _, err := undeclared.Get(ctx, user); err == nil {
+		t.Errorf("expected an error for an undeclared flag\n")
+	}
+}
@@ -1,8 +1,11 @@
 module github.com/nelz9999/goldhook
 
-go 1.17
+go 1.18
 
-require gopkg.in/launchdarkly/go-sdk-common.v2 v2.4.0
+require (
+	gopkg.in/launchdarkly/go-sdk-common.v2 v2.4.0
+	gopkg.in/launchdarkly/go-server-sdk.v5 v5.6.0
+)
 
 require (
 	github.com/google/uuid v1.1.1 // indirect
@@ -17,5 +20,4 @@ require (
 	gopkg.in/launchdarkly/go-jsonstream.v1 v1.0.1 // indirect
 	gopkg.in/launchdarkly/go-sdk-events.v1 v1.1.1 // indirect
 	gopkg.in/launchdarkly/go-server-sdk-evaluation.v1 v1.4.1 // indirect
-	gopkg.in/launchdarkly/go-server-sdk.v5 v5.6.0 // indirect
 )
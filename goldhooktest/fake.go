@@ -0,0 +1,222 @@
+// Package goldhooktest provides a scriptable fake of the LaunchDarkly client,
+// and an Observer which records evaluations and makes assertions about them,
+// so that observers and feature-gated code can be tested without the SDK.
+package goldhooktest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+)
+
+// Result is what a Fake serves for an evaluation.
+//
+// If Err is set, the callsite default is served along with Err, and a Reason
+// of ERROR/EXCEPTION unless another Reason is given. Otherwise Value is
+// served, with a Reason of FALLTHROUGH unless another Reason is given.
+// Latency is how long the evaluation takes.
+type Result struct {
+	Value          ldvalue.Value
+	VariationIndex ldvalue.OptionalInt
+	Reason         ldreason.EvaluationReason
+	Err            error
+	Latency        time.Duration
+}
+
+// Serve is the Result of a flag serving the given value and variation
+func Serve(value ldvalue.Value, variation int) Result {
+	return Result{
+		Value:          value,
+		VariationIndex: ldvalue.NewOptionalInt(variation),
+	}
+}
+
+// Fail is the Result of an evaluation which errs
+func Fail(err error) Result {
+	return Result{Err: err}
+}
+
+// WithReason returns a copy of the Result with the given Reason
+func (r Result) WithReason(reason ldreason.EvaluationReason) Result {
+	r.Reason = reason
+	return r
+}
+
+// WithLatency returns a copy of the Result which takes the given time
+func (r Result) WithLatency(latency time.Duration) Result {
+	r.Latency = latency
+	return r
+}
+
+// Fake implements the Evaluator interface, serving whatever it has been
+// scripted to per flag key, and optionally per user.
+// An evaluation of a flag key which has not been scripted errs as the SDK
+// does, with FLAG_NOT_FOUND, and one which asks for a type which doesn't
+// match the scripted value errs with WRONG_TYPE.
+type Fake struct {
+	mu    sync.RWMutex
+	flags map[string]Result
+	users map[string]map[string]Result
+}
+
+// NewFake has nothing scripted
+func NewFake() *Fake {
+	return &Fake{
+		flags: map[string]Result{},
+		users: map[string]map[string]Result{},
+	}
+}
+
+// Set scripts the Result for evaluations of the flag key, for any user
+// not otherwise scripted
+func (f *Fake) Set(key string, r Result) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags[key] = r
+	return f
+}
+
+// SetFor scripts the Result for evaluations of the flag key, for the user
+// with the given key
+func (f *Fake) SetFor(key, userKey string, r Result) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.users[key] == nil {
+		f.users[key] = map[string]Result{}
+	}
+	f.users[key][userKey] = r
+	return f
+}
+
+// Clear forgets everything scripted for the flag key
+func (f *Fake) Clear(key string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.flags, key)
+	delete(f.users, key)
+	return f
+}
+
+func (f *Fake) lookup(key string, user lduser.User) (Result, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if r, ok := f.users[key][user.GetKey()]; ok {
+		return r, true
+	}
+	r, ok := f.flags[key]
+	return r, ok
+}
+
+func (f *Fake) evaluate(
+	key string,
+	user lduser.User,
+	defaultVal ldvalue.Value,
+	kind goldhook.ValueKind,
+) (ldreason.EvaluationDetail, error) {
+	r, ok := f.lookup(key, user)
+	if !ok {
+		return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorFlagNotFound, defaultVal),
+			fmt.Errorf("unknown flag key %q", key)
+	}
+
+	time.Sleep(r.Latency)
+
+	if r.Err != nil {
+		detail := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, defaultVal)
+		if r.Reason.GetKind() != "" {
+			detail.Reason = r.Reason
+		}
+		return detail, r.Err
+	}
+	if !matches(r.Value, kind) {
+		return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorWrongType, defaultVal),
+			fmt.Errorf("flag %q is not of type %s", key, kind)
+	}
+	detail := ldreason.EvaluationDetail{
+		Value:          r.Value,
+		VariationIndex: r.VariationIndex,
+		Reason:         r.Reason,
+	}
+	if detail.Reason.GetKind() == "" {
+		detail.Reason = ldreason.NewEvalReasonFallthrough()
+	}
+	return detail, nil
+}
+
+func matches(v ldvalue.Value, kind goldhook.ValueKind) bool {
+	switch kind {
+	case goldhook.BoolKind:
+		return v.IsBool()
+	case goldhook.Float64Kind, goldhook.IntKind:
+		return v.IsNumber()
+	case goldhook.StringKind:
+		return v.IsString()
+	}
+	return true
+}
+
+// BoolVariation conforms to the Evaluator interface
+func (f *Fake) BoolVariation(key string, user lduser.User, defaultVal bool) (bool, error) {
+	result, _, err := f.BoolVariationDetail(key, user, defaultVal)
+	return result, err
+}
+
+// BoolVariationDetail conforms to the Evaluator interface
+func (f *Fake) BoolVariationDetail(key string, user lduser.User, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(key, user, ldvalue.Bool(defaultVal), goldhook.BoolKind)
+	return detail.Value.BoolValue(), detail, err
+}
+
+// Float64Variation conforms to the Evaluator interface
+func (f *Fake) Float64Variation(key string, user lduser.User, defaultVal float64) (float64, error) {
+	result, _, err := f.Float64VariationDetail(key, user, defaultVal)
+	return result, err
+}
+
+// Float64VariationDetail conforms to the Evaluator interface
+func (f *Fake) Float64VariationDetail(key string, user lduser.User, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(key, user, ldvalue.Float64(defaultVal), goldhook.Float64Kind)
+	return detail.Value.Float64Value(), detail, err
+}
+
+// IntVariation conforms to the Evaluator interface
+func (f *Fake) IntVariation(key string, user lduser.User, defaultVal int) (int, error) {
+	result, _, err := f.IntVariationDetail(key, user, defaultVal)
+	return result, err
+}
+
+// IntVariationDetail conforms to the Evaluator interface
+func (f *Fake) IntVariationDetail(key string, user lduser.User, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(key, user, ldvalue.Int(defaultVal), goldhook.IntKind)
+	return detail.Value.IntValue(), detail, err
+}
+
+// JSONVariation conforms to the Evaluator interface
+func (f *Fake) JSONVariation(key string, user lduser.User, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	result, _, err := f.JSONVariationDetail(key, user, defaultVal)
+	return result, err
+}
+
+// JSONVariationDetail conforms to the Evaluator interface
+func (f *Fake) JSONVariationDetail(key string, user lduser.User, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(key, user, defaultVal, goldhook.JSONKind)
+	return detail.Value, detail, err
+}
+
+// StringVariation conforms to the Evaluator interface
+func (f *Fake) StringVariation(key string, user lduser.User, defaultVal string) (string, error) {
+	result, _, err := f.StringVariationDetail(key, user, defaultVal)
+	return result, err
+}
+
+// StringVariationDetail conforms to the Evaluator interface
+func (f *Fake) StringVariationDetail(key string, user lduser.User, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(key, user, ldvalue.String(defaultVal), goldhook.StringKind)
+	return detail.Value.StringValue(), detail, err
+}
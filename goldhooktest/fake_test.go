@@ -0,0 +1,71 @@
+package goldhooktest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/goldhooktest"
+)
+
+func TestFake(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+	failure := fmt.Errorf("failed %s", now)
+
+	fake := goldhooktest.NewFake().
+		Set("enabled", goldhooktest.Serve(ldvalue.Bool(true), 1)).
+		SetFor("enabled", "vip-"+now, goldhooktest.Serve(ldvalue.Bool(false), 0).WithReason(ldreason.NewEvalReasonTargetMatch())).
+		Set("name", goldhooktest.Serve(ldvalue.String(now), 2)).
+		Set("failing", goldhooktest.Fail(failure))
+
+	var _ goldhook.Evaluator = fake
+
+	user := lduser.NewUser(now)
+	vip := lduser.NewUser("vip-" + now)
+
+	result, detail, err := fake.BoolVariationDetail("enabled", user, false)
+	if err != nil || !result || detail.VariationIndex.OrElse(-1) != 1 || detail.Reason.GetKind() != ldreason.EvalReasonFallthrough {
+		t.Errorf("user - unexpected: %v %+v %v\n", result, detail, err)
+	}
+	result, detail, err = fake.BoolVariationDetail("enabled", vip, true)
+	if err != nil || result || detail.VariationIndex.OrElse(-1) != 0 || detail.Reason.GetKind() != ldreason.EvalReasonTargetMatch {
+		t.Errorf("vip - unexpected: %v %+v %v\n", result, detail, err)
+	}
+
+	if s, err := fake.StringVariation("name", user, ""); err != nil || s != now {
+		t.Errorf("name - expected %q; got %q %v\n", now, s, err)
+	}
+	if i, detail, err := fake.IntVariationDetail("name", user, 42); err == nil || i != 42 || detail.Reason.GetErrorKind() != ldreason.EvalErrorWrongType {
+		t.Errorf("wrong type - unexpected: %d %+v %v\n", i, detail, err)
+	}
+	if s, detail, err := fake.StringVariationDetail("failing", user, now); err != failure || s != now || detail.Reason.GetErrorKind() != ldreason.EvalErrorException {
+		t.Errorf("failing - unexpected: %q %+v %v\n", s, detail, err)
+	}
+	if v, detail, err := fake.JSONVariationDetail("missing", user, ldvalue.Null()); err == nil || !v.IsNull() || detail.Reason.GetErrorKind() != ldreason.EvalErrorFlagNotFound {
+		t.Errorf("missing - unexpected: %v %+v %v\n", v, detail, err)
+	}
+
+	fake.Clear("name")
+	if _, err := fake.StringVariation("name", user, ""); err == nil {
+		t.Errorf("cleared - expected an error\n")
+	}
+}
+
+func TestFakeLatency(t *testing.T) {
+	latency := 50 * time.Millisecond
+	fake := goldhooktest.NewFake().Set("slow", goldhooktest.Serve(ldvalue.Int(7), 0).WithLatency(latency))
+	user := lduser.NewUser("user")
+
+	start := time.Now()
+	if i, err := fake.IntVariation("slow", user, 0); err != nil || i != 7 {
+		t.Errorf("unexpected: %d %v\n", i, err)
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Errorf("expected at least %v; got %v\n", latency, elapsed)
+	}
+}
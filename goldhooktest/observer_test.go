@@ -0,0 +1,76 @@
+package goldhooktest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/goldhooktest"
+)
+
+// catcher notes failures rather than failing the test
+type catcher struct {
+	testing.TB
+	failed bool
+}
+
+func (c *catcher) Errorf(string, ...interface{}) {
+	c.failed = true
+}
+
+func TestRecordingObserver(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := goldhooktest.NewFake().
+		Set("enabled", goldhooktest.Serve(ldvalue.Bool(true), 1)).
+		Set("off", goldhooktest.Serve(ldvalue.Bool(false), 0).WithReason(ldreason.NewEvalReasonOff()))
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(goldhook.Override("forced", ldvalue.String(now)))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUser(now)
+	hooked.BoolVariation("enabled", user, false)
+	hooked.BoolVariation("enabled", user, false)
+	hooked.BoolVariation("off", user, true)
+	hooked.StringVariation("forced", user, "")
+
+	recorder.AssertEvaluated(t, "enabled", 2)
+	recorder.AssertEvaluated(t, "missing", 0)
+	recorder.AssertReason(t, "enabled", ldreason.EvalReasonFallthrough, 2)
+	recorder.AssertReason(t, "off", ldreason.EvalReasonOff, 1)
+	recorder.AssertNoErrors(t)
+
+	evs := recorder.Evaluations()
+	if len(evs) != 4 {
+		t.Fatalf("expected %d; got %d\n", 4, len(evs))
+	}
+	if evs[0].User.GetKey() != now || !evs[0].Detail.Value.BoolValue() {
+		t.Errorf("unexpected: %+v\n", evs[0])
+	}
+	if evs[3].InterceptedBy != "override:forced" || evs[3].Detail.Value.StringValue() != now {
+		t.Errorf("unexpected: %+v\n", evs[3])
+	}
+
+	// the assertions fail as they should
+	hooked.StringVariation("missing", user, "")
+	failing := &catcher{TB: t}
+	recorder.AssertNoErrors(failing)
+	if !failing.failed {
+		t.Errorf("expected an error to be asserted\n")
+	}
+
+	recorder.Reset()
+	recorder.AssertEvaluated(t, "enabled", 0)
+}
@@ -0,0 +1,176 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+	ld "gopkg.in/launchdarkly/go-server-sdk.v5"
+
+	"github.com/nelz9999/goldhook"
+)
+
+func TestInterceptors(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	var lastBy string
+	var lastDetail ldreason.EvaluationDetail
+	var lastErr error
+	hook := goldhook.ObserverFunc(
+		func(
+			ctx context.Context,
+			_ string,
+			_ lduser.User,
+			_ ldvalue.Value,
+			_ time.Duration,
+			detail ldreason.EvaluationDetail,
+			evalErr error,
+		) {
+			lastBy, _ = goldhook.InterceptedBy(ctx)
+			lastDetail, lastErr = detail, evalErr
+		},
+	)
+
+	failure := fmt.Errorf("failure-%s", now)
+	failing := goldhook.NewInterceptor("failing", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+		return func(ctx context.Context, req goldhook.EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			if req.Key != "failing" {
+				return next(ctx, req)
+			}
+			return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, req.CallsiteDefault), failure
+		}
+	})
+	passthrough := goldhook.NewInterceptor("passthrough", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+		return next
+	})
+
+	hooked, err := goldhook.NewEvaluator(context.Background(), client, hook)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(
+		passthrough,
+		goldhook.DefaultOnError(),
+		goldhook.KillSwitch("killed"),
+		goldhook.Override("forced", ldvalue.String(now)),
+		failing,
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewAnonymousUser(now)
+
+	// nothing intercepts this, so the offline client serves the default
+	if result, _ := hooked.StringVariation("plain", user, "default"); result != "default" {
+		t.Errorf("plain - expected %q; got %q\n", "default", result)
+	}
+	if lastBy != "" {
+		t.Errorf("plain - expected no interceptor; got %q\n", lastBy)
+	}
+
+	if result, _ := hooked.StringVariation("forced", user, "default"); result != now {
+		t.Errorf("forced - expected %q; got %q\n", now, result)
+	}
+	if lastBy != "override:forced" {
+		t.Errorf("forced - expected %q; got %q\n", "override:forced", lastBy)
+	}
+
+	// the override is of the wrong type for a bool flag
+	if result, _ := hooked.BoolVariation("forced", user, true); !result {
+		t.Errorf("wrong type - expected %t; got %t\n", true, result)
+	}
+	if kind := lastDetail.Reason.GetErrorKind(); kind != ldreason.EvalErrorWrongType {
+		t.Errorf("wrong type - expected %q; got %q\n", ldreason.EvalErrorWrongType, kind)
+	}
+
+	if result, _ := hooked.IntVariation("killed", user, 42); result != 42 {
+		t.Errorf("killed - expected %d; got %d\n", 42, result)
+	}
+	if kind := lastDetail.Reason.GetKind(); kind != ldreason.EvalReasonOff {
+		t.Errorf("killed - expected %q; got %q\n", ldreason.EvalReasonOff, kind)
+	}
+	if lastBy != "killswitch" {
+		t.Errorf("killed - expected %q; got %q\n", "killswitch", lastBy)
+	}
+
+	// the outermost interceptor to change the result is the one reported
+	if _, err := hooked.StringVariation("failing", user, "default"); err != nil {
+		t.Errorf("failing - unexpected: %v\n", err)
+	}
+	if lastErr != nil {
+		t.Errorf("failing - unexpected observed: %v\n", lastErr)
+	}
+	if lastBy != "default-on-error" {
+		t.Errorf("failing - expected %q; got %q\n", "default-on-error", lastBy)
+	}
+}
+
+func TestInterceptorsNil(t *testing.T) {
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err := goldhook.NewEvaluator(context.Background(), client)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, err := hooked.WithInterceptors(nil); err == nil {
+		t.Errorf("expected error for nil interceptor\n")
+	}
+}
+
+// uncomparable is an error type which panics when compared with ==
+type uncomparable []string
+
+func (u uncomparable) Error() string {
+	return fmt.Sprintf("%v", []string(u))
+}
+
+func TestInterceptorsUncomparableError(t *testing.T) {
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	var lastBy string
+	hook := goldhook.ObserverFunc(
+		func(ctx context.Context, _ string, _ lduser.User, _ ldvalue.Value, _ time.Duration, _ ldreason.EvaluationDetail, _ error) {
+			lastBy, _ = goldhook.InterceptedBy(ctx)
+		},
+	)
+
+	failing := goldhook.NewInterceptor("failing", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+		return func(ctx context.Context, req goldhook.EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, req.CallsiteDefault), uncomparable{"a", "b"}
+		}
+	})
+	passthrough := goldhook.NewInterceptor("passthrough", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+		return next
+	})
+
+	hooked, err := goldhook.NewEvaluator(context.Background(), client, hook)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(passthrough, failing)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	if _, err := hooked.BoolVariation("any", lduser.NewUser("u"), false); err == nil {
+		t.Errorf("expected error\n")
+	}
+	if lastBy != "failing" {
+		t.Errorf("expected %q; got %q\n", "failing", lastBy)
+	}
+}
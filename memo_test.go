@@ -0,0 +1,155 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/goldhooktest"
+)
+
+func TestMemoize(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := goldhooktest.NewFake().Set("color", goldhooktest.Serve(ldvalue.String("red"), 0))
+	events := []goldhook.EvaluationEvent{}
+	recorder := goldhook.EventObserverFunc(func(_ context.Context, ev goldhook.EvaluationEvent) {
+		events = append(events, ev)
+	})
+	hooked, err := goldhook.NewEventEvaluator(context.Background(), fake, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(goldhook.Memoize())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user, other := lduser.NewUser(now), lduser.NewUser("other")
+	scope := goldhook.WithMemoization(context.Background())
+
+	first, _ := hooked.WithContext(scope).StringVariation("color", user, "")
+	// the flag changes part way through the request
+	fake.Set("color", goldhooktest.Serve(ldvalue.String("blue"), 1))
+	second, _ := hooked.WithContext(scope).StringVariation("color", user, "")
+	if first != "red" || second != "red" {
+		t.Errorf("expected %q twice; got %q and %q\n", "red", first, second)
+	}
+
+	// other users and types are memoized separately
+	if s, _ := hooked.WithContext(scope).StringVariation("color", other, ""); s != "blue" {
+		t.Errorf("other - expected %q; got %q\n", "blue", s)
+	}
+	if v, _ := hooked.WithContext(scope).JSONVariation("color", user, ldvalue.Null()); v.StringValue() != "blue" {
+		t.Errorf("json - expected %q; got %v\n", "blue", v)
+	}
+
+	// a new scope, or no scope, sees the change
+	if s, _ := hooked.WithContext(goldhook.WithMemoization(context.Background())).StringVariation("color", user, ""); s != "blue" {
+		t.Errorf("new scope - expected %q; got %q\n", "blue", s)
+	}
+	if s, _ := hooked.StringVariation("color", user, ""); s != "blue" {
+		t.Errorf("no scope - expected %q; got %q\n", "blue", s)
+	}
+
+	if len(events) != 6 {
+		t.Fatalf("expected %d events; got %d\n", 6, len(events))
+	}
+	for i, ev := range events {
+		memoized := ev.Extensions[goldhook.MemoizedExtension] == true
+		if memoized != (i == 1) {
+			t.Errorf("%d - expected memoized %t; got %+v\n", i, i == 1, ev)
+		}
+		if memoized && ev.InterceptedBy != "memoize" {
+			t.Errorf("%d - expected %q; got %q\n", i, "memoize", ev.InterceptedBy)
+		}
+	}
+}
+
+func TestMemoizeMatches(t *testing.T) {
+	fake := goldhooktest.NewFake().Set("color", goldhooktest.Serve(ldvalue.String("red"), 0))
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(goldhook.Memoize())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	scoped := hooked.WithContext(goldhook.WithMemoization(context.Background()))
+
+	user := lduser.NewUser("memo")
+	scoped.StringVariation("color", user, "")
+	fake.Set("color", goldhooktest.Serve(ldvalue.String("blue"), 1))
+
+	// the same key with other attributes is evaluated afresh
+	if s, _ := scoped.StringVariation("color", lduser.NewUserBuilder("memo").Country("nz").Build(), ""); s != "blue" {
+		t.Errorf("attributes - expected %q; got %q\n", "blue", s)
+	}
+	// but another default is not, being no reason for the value to change
+	if s, _ := scoped.StringVariation("color", user, "none"); s != "red" {
+		t.Errorf("default - expected %q; got %q\n", "red", s)
+	}
+	if s, _ := scoped.StringVariation("color", user, ""); s != "red" {
+		t.Errorf("memoized - expected %q; got %q\n", "red", s)
+	}
+
+	// an error serves each callsite its own default
+	scoped.StringVariation("missing", user, "first")
+	fake.Set("missing", goldhooktest.Serve(ldvalue.String("found"), 0))
+	s, detail, err := scoped.StringVariationDetail("missing", user, "second")
+	if s != "second" || !detail.Value.Equal(ldvalue.String("second")) {
+		t.Errorf("error - expected %q; got %q (%v)\n", "second", s, detail.Value)
+	}
+	if err == nil || detail.Reason.GetErrorKind() != ldreason.EvalErrorFlagNotFound {
+		t.Errorf("error - expected the memoized error; got %v (%v)\n", err, detail.Reason)
+	}
+}
+
+func TestMemoizeWaiting(t *testing.T) {
+	fake := goldhooktest.NewFake().Set("color", goldhooktest.Serve(ldvalue.String("red"), 0))
+	started, gate := make(chan struct{}), make(chan struct{})
+	gated := goldhook.NewInterceptor("gated", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+		return func(ctx context.Context, req goldhook.EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			close(started)
+			<-gate
+			return next(ctx, req)
+		}
+	})
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(goldhook.Memoize(), gated)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	scope := goldhook.WithMemoization(context.Background())
+	user := lduser.NewUser("memo")
+
+	done := make(chan string)
+	go func() {
+		s, _ := hooked.WithContext(scope).StringVariation("color", user, "")
+		done <- s
+	}()
+	<-started
+
+	// another evaluation gives up waiting on the first once its context is done
+	waiting, cancel := context.WithTimeout(scope, 10*time.Millisecond)
+	defer cancel()
+	s, err := hooked.WithContext(waiting).StringVariation("color", user, "")
+	if s != "" || err != context.DeadlineExceeded {
+		t.Errorf("waiting - expected %q with %v; got %q with %v\n", "", context.DeadlineExceeded, s, err)
+	}
+
+	close(gate)
+	if s := <-done; s != "red" {
+		t.Errorf("first - expected %q; got %q\n", "red", s)
+	}
+}
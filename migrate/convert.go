@@ -0,0 +1,230 @@
+// Package migrate converts between the users, values and details of the v5
+// (root) goldhook module and the contexts, values and details of the v6
+// module, and adapts the Observers of each to the other, so that telemetry
+// need not be rewritten when moving between SDK versions.
+package migrate
+
+import (
+	"github.com/launchdarkly/go-sdk-common/v3/ldattr"
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ldreasonV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	ldvalueV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// builtIns are the string attributes a user has beyond its key, name and
+// anonymity, which become attributes of the same name on a context
+var builtIns = []lduser.UserAttribute{
+	lduser.IPAttribute,
+	lduser.CountryAttribute,
+	lduser.EmailAttribute,
+	lduser.FirstNameAttribute,
+	lduser.LastNameAttribute,
+	lduser.AvatarAttribute,
+}
+
+// UserContext converts a v5 user into the equivalent context of kind "user".
+// Its built-in and custom attributes become attributes of the context, and
+// those that are private stay private. As in the v6+ SDKs, custom attributes
+// which would collide with the context's own (such as "kind" or "key") are
+// dropped, as is the secondary key, which contexts no longer support.
+func UserContext(u lduser.User) ldcontext.Context {
+	b := ldcontext.NewBuilder(u.GetKey())
+	private := func(attr lduser.UserAttribute) {
+		if u.IsPrivateAttribute(attr) {
+			b.PrivateRef(ldattr.NewLiteralRef(string(attr)))
+		}
+	}
+
+	b.OptName(optionalV6(u.GetName()))
+	private(lduser.NameAttribute)
+	b.Anonymous(u.GetAnonymous())
+	for _, attr := range builtIns {
+		if s := u.GetAttribute(attr); s.IsString() {
+			b.SetString(string(attr), s.StringValue())
+			private(attr)
+		}
+	}
+	for name, value := range u.GetAllCustomMap().AsMap() {
+		switch name {
+		case ldattr.KindAttr, ldattr.KeyAttr, ldattr.NameAttr, ldattr.AnonymousAttr, "_meta":
+			continue
+		}
+		b.SetValue(name, ValueV6(value))
+		private(lduser.UserAttribute(name))
+	}
+	return b.Build()
+}
+
+// ContextUser converts a context into the nearest v5 user. Its attributes
+// become built-in or custom attributes of the user, and those that are private
+// stay private (though only top-level attributes can be private to a user).
+//
+// Users have no kinds, so a multi-kind context is represented by its context
+// of kind "user", if it has one; any other context is represented by a user
+// keyed by its fully-qualified key, without attributes.
+func ContextUser(ldctx ldcontext.Context) lduser.User {
+	if ldctx.Multiple() {
+		if uc := ldctx.IndividualContextByKind(ldcontext.DefaultKind); uc.IsDefined() {
+			ldctx = uc
+		}
+	}
+	if ldctx.Kind() != ldcontext.DefaultKind {
+		return lduser.NewUser(ldctx.FullyQualifiedKey())
+	}
+
+	private := map[string]bool{}
+	for i := 0; i < ldctx.PrivateAttributeCount(); i++ {
+		if ref, ok := ldctx.PrivateAttributeByIndex(i); ok && ref.Depth() == 1 {
+			private[ref.Component(0)] = true
+		}
+	}
+
+	b := lduser.NewUserBuilder(ldctx.Key())
+	b.Anonymous(ldctx.Anonymous())
+	if secondary := ldctx.Secondary(); secondary.IsDefined() {
+		b.Secondary(secondary.StringValue())
+	}
+	for _, name := range ldctx.GetOptionalAttributeNames(nil) {
+		ab := b.SetAttribute(lduser.UserAttribute(name), ValueV5(ldctx.GetValue(name)))
+		if private[name] {
+			ab.AsPrivateAttribute()
+		}
+	}
+	return b.Build()
+}
+
+// ValueV6 converts a v5 value into the equivalent v6 value.
+func ValueV6(v ldvalueV2.Value) ldvalue.Value {
+	switch v.Type() {
+	case ldvalueV2.BoolType:
+		return ldvalue.Bool(v.BoolValue())
+	case ldvalueV2.NumberType:
+		return ldvalue.Float64(v.Float64Value())
+	case ldvalueV2.StringType:
+		return ldvalue.String(v.StringValue())
+	case ldvalueV2.ArrayType:
+		b := ldvalue.ArrayBuildWithCapacity(v.Count())
+		for i := 0; i < v.Count(); i++ {
+			b.Add(ValueV6(v.GetByIndex(i)))
+		}
+		return b.Build()
+	case ldvalueV2.ObjectType:
+		b := ldvalue.ObjectBuildWithCapacity(v.Count())
+		for _, key := range v.Keys() {
+			b.Set(key, ValueV6(v.GetByKey(key)))
+		}
+		return b.Build()
+	default:
+		return ldvalue.Null()
+	}
+}
+
+// ValueV5 converts a v6 value into the equivalent v5 value.
+func ValueV5(v ldvalue.Value) ldvalueV2.Value {
+	switch v.Type() {
+	case ldvalue.BoolType:
+		return ldvalueV2.Bool(v.BoolValue())
+	case ldvalue.NumberType:
+		return ldvalueV2.Float64(v.Float64Value())
+	case ldvalue.StringType:
+		return ldvalueV2.String(v.StringValue())
+	case ldvalue.ArrayType:
+		b := ldvalueV2.ArrayBuildWithCapacity(v.Count())
+		for i := 0; i < v.Count(); i++ {
+			b.Add(ValueV5(v.GetByIndex(i)))
+		}
+		return b.Build()
+	case ldvalue.ObjectType:
+		b := ldvalueV2.ObjectBuildWithCapacity(v.Count())
+		for _, key := range v.Keys(nil) {
+			b.Set(key, ValueV5(v.GetByKey(key)))
+		}
+		return b.Build()
+	default:
+		return ldvalueV2.Null()
+	}
+}
+
+// DetailV6 converts a v5 evaluation detail into the equivalent v6 detail.
+func DetailV6(d ldreasonV2.EvaluationDetail) ldreason.EvaluationDetail {
+	index := ldvalue.OptionalInt{}
+	if i, ok := d.VariationIndex.Get(); ok {
+		index = ldvalue.NewOptionalInt(i)
+	}
+	return ldreason.EvaluationDetail{
+		Value:          ValueV6(d.Value),
+		VariationIndex: index,
+		Reason:         reasonV6(d.Reason),
+	}
+}
+
+// DetailV5 converts a v6 evaluation detail into the equivalent v5 detail.
+func DetailV5(d ldreason.EvaluationDetail) ldreasonV2.EvaluationDetail {
+	index := ldvalueV2.OptionalInt{}
+	if i, ok := d.VariationIndex.Get(); ok {
+		index = ldvalueV2.NewOptionalInt(i)
+	}
+	return ldreasonV2.EvaluationDetail{
+		Value:          ValueV5(d.Value),
+		VariationIndex: index,
+		Reason:         reasonV5(d.Reason),
+	}
+}
+
+func reasonV6(r ldreasonV2.EvaluationReason) ldreason.EvaluationReason {
+	var reason ldreason.EvaluationReason
+	switch r.GetKind() {
+	case ldreasonV2.EvalReasonOff:
+		reason = ldreason.NewEvalReasonOff()
+	case ldreasonV2.EvalReasonFallthrough:
+		reason = ldreason.NewEvalReasonFallthroughExperiment(r.IsInExperiment())
+	case ldreasonV2.EvalReasonTargetMatch:
+		reason = ldreason.NewEvalReasonTargetMatch()
+	case ldreasonV2.EvalReasonRuleMatch:
+		reason = ldreason.NewEvalReasonRuleMatchExperiment(r.GetRuleIndex(), r.GetRuleID(), r.IsInExperiment())
+	case ldreasonV2.EvalReasonPrerequisiteFailed:
+		reason = ldreason.NewEvalReasonPrerequisiteFailed(r.GetPrerequisiteKey())
+	case ldreasonV2.EvalReasonError:
+		reason = ldreason.NewEvalReasonError(ldreason.EvalErrorKind(r.GetErrorKind()))
+	default:
+		return reason
+	}
+	if status := r.GetBigSegmentsStatus(); status != "" {
+		reason = ldreason.NewEvalReasonFromReasonWithBigSegmentsStatus(reason, ldreason.BigSegmentsStatus(status))
+	}
+	return reason
+}
+
+func reasonV5(r ldreason.EvaluationReason) ldreasonV2.EvaluationReason {
+	var reason ldreasonV2.EvaluationReason
+	switch r.GetKind() {
+	case ldreason.EvalReasonOff:
+		reason = ldreasonV2.NewEvalReasonOff()
+	case ldreason.EvalReasonFallthrough:
+		reason = ldreasonV2.NewEvalReasonFallthroughExperiment(r.IsInExperiment())
+	case ldreason.EvalReasonTargetMatch:
+		reason = ldreasonV2.NewEvalReasonTargetMatch()
+	case ldreason.EvalReasonRuleMatch:
+		reason = ldreasonV2.NewEvalReasonRuleMatchExperiment(r.GetRuleIndex(), r.GetRuleID(), r.IsInExperiment())
+	case ldreason.EvalReasonPrerequisiteFailed:
+		reason = ldreasonV2.NewEvalReasonPrerequisiteFailed(r.GetPrerequisiteKey())
+	case ldreason.EvalReasonError:
+		reason = ldreasonV2.NewEvalReasonError(ldreasonV2.EvalErrorKind(r.GetErrorKind()))
+	default:
+		return reason
+	}
+	if status := r.GetBigSegmentsStatus(); status != "" {
+		reason = ldreasonV2.NewEvalReasonFromReasonWithBigSegmentsStatus(reason, ldreasonV2.BigSegmentsStatus(status))
+	}
+	return reason
+}
+
+func optionalV6(s ldvalueV2.OptionalString) ldvalue.OptionalString {
+	if v, ok := s.Get(); ok {
+		return ldvalue.NewOptionalString(v)
+	}
+	return ldvalue.OptionalString{}
+}
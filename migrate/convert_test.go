@@ -0,0 +1,163 @@
+package migrate_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ldreasonV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	ldvalueV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook/migrate"
+)
+
+func TestUserContext(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	user := lduser.NewUserBuilder(now).
+		Name("Someone").
+		Email("someone@example.com").AsPrivateAttribute().
+		Country("NZ").
+		Custom("tier", ldvalueV2.String("gold")).
+		Custom("seats", ldvalueV2.Int(5)).AsPrivateAttribute().
+		Anonymous(true).
+		Build()
+
+	ldctx := migrate.UserContext(user)
+	if err := ldctx.Err(); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if ldctx.Kind() != ldcontext.DefaultKind || ldctx.Key() != now || !ldctx.Anonymous() {
+		t.Errorf("unexpected: %v\n", ldctx)
+	}
+	expected := map[string]string{
+		"name":    `"Someone"`,
+		"email":   `"someone@example.com"`,
+		"country": `"NZ"`,
+		"tier":    `"gold"`,
+		"seats":   `5`,
+	}
+	for name, value := range expected {
+		if v := ldctx.GetValue(name); v.JSONString() != value {
+			t.Errorf("%s - expected %s; got %s\n", name, value, v.JSONString())
+		}
+	}
+	private := map[string]bool{}
+	for i := 0; i < ldctx.PrivateAttributeCount(); i++ {
+		ref, _ := ldctx.PrivateAttributeByIndex(i)
+		private[ref.String()] = true
+	}
+	if len(private) != 2 || !private["email"] || !private["seats"] {
+		t.Errorf("unexpected private attributes: %v\n", private)
+	}
+
+	// and back again
+	back := migrate.ContextUser(ldctx)
+	if !back.Equal(user) {
+		t.Errorf("expected %v; got %v\n", user, back)
+	}
+}
+
+func TestContextUser(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	org := ldcontext.NewWithKind("org", "org-"+now)
+	user := ldcontext.NewBuilder(now).
+		SetString("plan", "pro").
+		SetString("email", "someone@example.com").
+		Private("plan").
+		Build()
+
+	// a multi-kind context is represented by its user
+	u := migrate.ContextUser(ldcontext.NewMulti(org, user))
+	if u.GetKey() != now || u.GetEmail().StringValue() != "someone@example.com" {
+		t.Errorf("unexpected: %v\n", u)
+	}
+	if v, ok := u.GetCustom("plan"); !ok || v.StringValue() != "pro" || !u.IsPrivateAttribute("plan") {
+		t.Errorf("unexpected: %v\n", u)
+	}
+
+	// any other context by its fully-qualified key
+	if u := migrate.ContextUser(org); u.GetKey() != "org:org-"+now {
+		t.Errorf("unexpected: %v\n", u)
+	}
+}
+
+func TestUserContextJSON(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	users := []lduser.User{
+		lduser.NewUser(now),
+		lduser.NewAnonymousUser(now),
+		lduser.NewUserBuilder(now).
+			Name("Someone").AsPrivateAttribute().
+			IP("127.0.0.1").
+			FirstName("Some").
+			LastName("One").AsPrivateAttribute().
+			Avatar("https://example.com/someone.png").
+			Custom("nested", ldvalueV2.ObjectBuild().
+				Set("list", ldvalueV2.ArrayOf(ldvalueV2.Int(1), ldvalueV2.Float64(2.5), ldvalueV2.Null())).
+				Set("on", ldvalueV2.Bool(true)).
+				Build()).
+			Custom("kind", ldvalueV2.String("collides")).
+			Build(),
+	}
+	// the conversion matches what the v6+ SDKs make of the user's JSON
+	for _, user := range users {
+		data, err := json.Marshal(user)
+		if err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+		var expected ldcontext.Context
+		if err := json.Unmarshal(data, &expected); err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+		if ldctx := migrate.UserContext(user); !ldctx.Equal(expected) {
+			t.Errorf("expected %v; got %v\n", expected, ldctx)
+		}
+	}
+}
+
+func TestDetails(t *testing.T) {
+	value := ldvalue.ObjectBuild().
+		Set("list", ldvalue.ArrayOf(ldvalue.Int(1), ldvalue.String("two"))).
+		Set("off", ldvalue.Bool(false)).
+		Build()
+	valueV5 := ldvalueV2.ObjectBuild().
+		Set("list", ldvalueV2.ArrayOf(ldvalueV2.Int(1), ldvalueV2.String("two"))).
+		Set("off", ldvalueV2.Bool(false)).
+		Build()
+	reasons := []ldreason.EvaluationReason{
+		{},
+		ldreason.NewEvalReasonOff(),
+		ldreason.NewEvalReasonFallthroughExperiment(true),
+		ldreason.NewEvalReasonTargetMatch(),
+		ldreason.NewEvalReasonRuleMatchExperiment(2, "rule", true),
+		ldreason.NewEvalReasonPrerequisiteFailed("prereq"),
+		ldreason.NewEvalReasonError(ldreason.EvalErrorWrongType),
+		ldreason.NewEvalReasonFromReasonWithBigSegmentsStatus(ldreason.NewEvalReasonTargetMatch(), ldreason.BigSegmentsStale),
+	}
+	for _, reason := range reasons {
+		detail := ldreason.NewEvaluationDetail(value, 1, reason)
+		v5 := migrate.DetailV5(detail)
+		if v5.Reason.String() != reason.String() || !v5.Value.Equal(valueV5) || v5.VariationIndex.IntValue() != 1 {
+			t.Errorf("%s - unexpected: %+v\n", reason, v5)
+		}
+		if v5.Reason.GetBigSegmentsStatus() != ldreasonV2.BigSegmentsStatus(reason.GetBigSegmentsStatus()) {
+			t.Errorf("%s - unexpected: %s\n", reason, v5.Reason.GetBigSegmentsStatus())
+		}
+		if back := migrate.DetailV6(v5); !back.Value.Equal(detail.Value) || back.Reason != detail.Reason || back.VariationIndex != detail.VariationIndex {
+			t.Errorf("%s - expected %+v; got %+v\n", reason, detail, back)
+		}
+	}
+
+	// an absent variation stays absent
+	if d := migrate.DetailV6(ldreasonV2.NewEvaluationDetailForError(ldreasonV2.EvalErrorFlagNotFound, ldvalueV2.Null())); d.VariationIndex.IsDefined() || d.Reason.GetErrorKind() != ldreason.EvalErrorFlagNotFound {
+		t.Errorf("unexpected: %+v\n", d)
+	}
+}
@@ -0,0 +1,26 @@
+module github.com/nelz9999/goldhook/migrate
+
+go 1.21
+
+require (
+	github.com/launchdarkly/go-sdk-common/v3 v3.1.0
+	github.com/nelz9999/goldhook v0.0.0-00010101000000-000000000000
+	github.com/nelz9999/goldhook/v6 v6.0.0-00010101000000-000000000000
+	gopkg.in/launchdarkly/go-sdk-common.v2 v2.4.0
+)
+
+require (
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/launchdarkly/go-jsonstream/v3 v3.0.0 // indirect
+	github.com/mailru/easyjson v0.7.6 // indirect
+	golang.org/x/exp v0.0.0-20220823124025-807a23277127 // indirect
+	gopkg.in/launchdarkly/go-jsonstream.v1 v1.0.1 // indirect
+	gopkg.in/launchdarkly/go-server-sdk.v5 v5.6.0 // indirect
+)
+
+// the conversions are developed alongside the modules they convert between,
+// which are only required at released versions once they are tagged
+replace (
+	github.com/nelz9999/goldhook => ../
+	github.com/nelz9999/goldhook/v6 => ../v6
+)
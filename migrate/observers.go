@@ -0,0 +1,49 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ldreasonV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	ldvalueV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	v5 "github.com/nelz9999/goldhook"
+	v6 "github.com/nelz9999/goldhook/v6"
+)
+
+// ObserverV6 adapts a v5 (root module) Observer, so that it can be registered
+// with a v6 ObservedEvaluator. Each context is handed to it as per ContextUser.
+func ObserverV6(o v5.Observer) v6.Observer {
+	return v6.ObserverFunc(func(
+		ctx context.Context,
+		key string,
+		ldctx ldcontext.Context,
+		callsiteDefault ldvalue.Value,
+		elapsed time.Duration,
+		detail ldreason.EvaluationDetail,
+		evalErr error,
+	) {
+		o.Observe(ctx, key, ContextUser(ldctx), ValueV5(callsiteDefault), elapsed, DetailV5(detail), evalErr)
+	})
+}
+
+// ObserverV5 adapts a v6 Observer, so that it can be registered with a v5
+// (root module) ObservedEvaluator. Each user is handed to it as per
+// UserContext.
+func ObserverV5(o v6.Observer) v5.Observer {
+	return v5.ObserverFunc(func(
+		ctx context.Context,
+		key string,
+		user lduser.User,
+		callsiteDefault ldvalueV2.Value,
+		elapsed time.Duration,
+		detail ldreasonV2.EvaluationDetail,
+		evalErr error,
+	) {
+		o.Observe(ctx, key, UserContext(user), ValueV6(callsiteDefault), elapsed, DetailV6(detail), evalErr)
+	})
+}
@@ -0,0 +1,80 @@
+package migrate_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ldreasonV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	ldvalueV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	v5 "github.com/nelz9999/goldhook"
+	v5test "github.com/nelz9999/goldhook/goldhooktest"
+	"github.com/nelz9999/goldhook/migrate"
+	v6 "github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+func TestObserverV5(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := v5test.NewFake().
+		Set("banner-"+now, v5test.Serve(ldvalueV2.String(now), 2).WithReason(ldreasonV2.NewEvalReasonTargetMatch()))
+
+	// the one v6 observer serves a v5 evaluator too
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := v5.NewEvaluator(context.Background(), fake, migrate.ObserverV5(recorder))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUserBuilder(now).Custom("tier", ldvalueV2.String("gold")).Build()
+	hooked.StringVariation("banner-"+now, user, "plain")
+	hooked.IntVariation("missing-"+now, user, 7)
+
+	recorder.AssertEvaluated(t, "banner-"+now, 1)
+	recorder.AssertReason(t, "banner-"+now, ldreason.EvalReasonTargetMatch, 1)
+	ev := recorder.Of("banner-" + now)[0]
+	if ev.Context.Key() != now || ev.Context.GetValue("tier").StringValue() != "gold" {
+		t.Errorf("unexpected context: %v\n", ev.Context)
+	}
+	if ev.CallsiteDefault.StringValue() != "plain" || ev.Detail.Value.StringValue() != now || ev.Detail.VariationIndex.IntValue() != 2 {
+		t.Errorf("unexpected: %+v\n", ev)
+	}
+	missing := recorder.Of("missing-" + now)
+	if len(missing) != 1 || missing[0].Err == nil || missing[0].Detail.Reason.GetErrorKind() != ldreason.EvalErrorFlagNotFound {
+		t.Errorf("unexpected: %+v\n", missing)
+	}
+}
+
+func TestObserverV6(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := goldhooktest.NewFake().
+		Set("checkout-"+now, goldhooktest.Serve(ldvalue.Bool(true), 1))
+
+	// the one v5 observer serves a v6 evaluator too
+	recorder := v5test.NewRecordingObserver()
+	hooked, err := v6.NewEvaluator(context.Background(), fake, migrate.ObserverV6(recorder))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	ldctx := ldcontext.NewBuilder(now).SetString("tier", "gold").Private("tier").Build()
+	hooked.BoolVariation("checkout-"+now, ldctx, false)
+
+	recorder.AssertEvaluated(t, "checkout-"+now, 1)
+	recorder.AssertNoErrors(t)
+	ev := recorder.Of("checkout-" + now)[0]
+	if ev.User.GetKey() != now || !ev.User.IsPrivateAttribute("tier") {
+		t.Errorf("unexpected user: %v\n", ev.User)
+	}
+	if !ev.Detail.Value.BoolValue() || ev.Detail.VariationIndex.IntValue() != 1 || ev.Detail.Reason.GetKind() != ldreasonV2.EvalReasonFallthrough {
+		t.Errorf("unexpected: %+v\n", ev)
+	}
+}
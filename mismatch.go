@@ -0,0 +1,122 @@
+package goldhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// Usage describes how a flag was asked for: which type of value was
+// requested, and what default was given. Callsite is the file and line from
+// which it was first asked for that way (as per FlagViolation.Callsite).
+type Usage struct {
+	Kind     ValueKind
+	Default  ldvalue.Value
+	Callsite string
+}
+
+// Mismatch describes a flag key which has been asked for in ways that
+// disagree with one another, ordered by when each was first seen.
+type Mismatch struct {
+	Key    string
+	Usages []Usage
+
+	// Truncated is true if more distinct usages were seen than the
+	// MismatchDetector keeps
+	Truncated bool
+}
+
+// MismatchDetector is an EventObserver which records each distinct callsite
+// default and requested type per flag key, so as to catch different parts of
+// the code which disagree about what a flag should be. It needs the requested
+// type, which plain Observers are not given, so it is registered via
+// NewEventEvaluator or StagedEvents. Flags included in AllFlagsState are
+// ignored, as they have no callsite.
+//
+// The Callsite of a Usage is only found if the MismatchDetector observes
+// synchronously, i.e. not behind an AsyncObserver.
+type MismatchDetector struct {
+	onMismatch func(ctx context.Context, m Mismatch)
+	limit      int
+
+	mu        sync.Mutex
+	flags     map[string][]Usage
+	truncated map[string]bool
+}
+
+// NewMismatchDetector invokes onMismatch (if it is not nil) whenever a flag
+// key is asked for in a way which disagrees with those seen before it. It is
+// invoked synchronously, with the context of the evaluation.
+//
+// At most limit distinct usages are kept per flag key (or any number, if
+// limit is not positive), so that one whose default is computed at runtime
+// cannot grow the detector without bound. Those seen beyond it are dropped,
+// and the Mismatch marked Truncated.
+func NewMismatchDetector(limit int, onMismatch func(ctx context.Context, m Mismatch)) *MismatchDetector {
+	return &MismatchDetector{
+		onMismatch: onMismatch,
+		limit:      limit,
+		flags:      map[string][]Usage{},
+		truncated:  map[string]bool{},
+	}
+}
+
+// ObserveEvent conforms to the EventObserver interface
+func (md *MismatchDetector) ObserveEvent(ctx context.Context, ev EvaluationEvent) {
+	if ev.Kind == AllFlagsKind {
+		// there is no callsite to disagree with
+		return
+	}
+	m, ok := md.record(ev.Key, Usage{Kind: ev.Kind, Default: ev.CallsiteDefault})
+	if ok && md.onMismatch != nil {
+		md.onMismatch(ctx, m)
+	}
+}
+
+// record adds the usage to those seen for the key, and reports whether it is
+// a new one which makes for a mismatch. Once the limit has been reached, any
+// more are only noted as truncating the Mismatch.
+func (md *MismatchDetector) record(key string, u Usage) (Mismatch, bool) {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+	seen := md.flags[key]
+	for _, s := range seen {
+		if s.Kind == u.Kind && s.Default.Equal(u.Default) {
+			return Mismatch{}, false
+		}
+	}
+	if md.limit > 0 && len(seen) >= md.limit {
+		md.truncated[key] = true
+		return Mismatch{}, false
+	}
+	// only a new usage is worth walking the stack for
+	u.Callsite = callsite()
+	seen = append(seen, u)
+	md.flags[key] = seen
+	if len(seen) < 2 {
+		return Mismatch{}, false
+	}
+	return Mismatch{Key: key, Usages: append([]Usage{}, seen...)}, true
+}
+
+// Report lists the flag keys which have mismatched usages, ordered by key
+func (md *MismatchDetector) Report() []Mismatch {
+	md.mu.Lock()
+	defer md.mu.Unlock()
+	report := []Mismatch{}
+	for key, seen := range md.flags {
+		if len(seen) > 1 {
+			report = append(report, Mismatch{
+				Key:       key,
+				Usages:    append([]Usage{}, seen...),
+				Truncated: md.truncated[key],
+			})
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Key < report[j].Key
+	})
+	return report
+}
@@ -0,0 +1,109 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+	ld "gopkg.in/launchdarkly/go-server-sdk.v5"
+
+	"github.com/nelz9999/goldhook"
+)
+
+func TestMismatchDetector(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	mismatches := []goldhook.Mismatch{}
+	detector := goldhook.NewMismatchDetector(0, func(_ context.Context, m goldhook.Mismatch) {
+		mismatches = append(mismatches, m)
+	})
+	hooked, err := goldhook.NewEventEvaluator(context.Background(), client, detector)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewAnonymousUser(now)
+	hooked.BoolVariation("consistent", user, false)
+	hooked.BoolVariation("consistent", user, false)
+	hooked.BoolVariation("defaults", user, false)
+	hooked.BoolVariation("defaults", user, true)
+	hooked.BoolVariation("defaults", user, false)
+	hooked.IntVariation("kinds", user, 1)
+	hooked.Float64Variation("kinds", user, 1)
+	hooked.StringVariation("kinds", user, now)
+
+	if len(mismatches) != 3 {
+		t.Fatalf("callbacks - expected %d; got %d: %+v\n", 3, len(mismatches), mismatches)
+	}
+	if mismatches[0].Key != "defaults" || len(mismatches[0].Usages) != 2 {
+		t.Errorf("defaults - unexpected: %+v\n", mismatches[0])
+	}
+	if mismatches[2].Key != "kinds" || len(mismatches[2].Usages) != 3 {
+		t.Errorf("kinds - unexpected: %+v\n", mismatches[2])
+	}
+
+	report := detector.Report()
+	if len(report) != 2 {
+		t.Fatalf("report - expected %d; got %d: %+v\n", 2, len(report), report)
+	}
+	defaults, kinds := report[0], report[1]
+	if defaults.Key != "defaults" {
+		t.Errorf("defaults - unexpected: %+v\n", defaults)
+	}
+	for i, expected := range []bool{false, true} {
+		u := defaults.Usages[i]
+		if u.Kind != goldhook.BoolKind || !u.Default.Equal(ldvalue.Bool(expected)) {
+			t.Errorf("defaults %d - expected %v; got %+v\n", i, expected, u)
+		}
+		// each is found where it was first asked for, in this file
+		if !strings.Contains(u.Callsite, "mismatch_test.go:") {
+			t.Errorf("defaults %d - unexpected callsite %q\n", i, u.Callsite)
+		}
+	}
+	if kinds.Key != "kinds" {
+		t.Errorf("kinds - unexpected: %+v\n", kinds)
+	}
+	for i, expected := range []goldhook.ValueKind{goldhook.IntKind, goldhook.Float64Kind, goldhook.StringKind} {
+		if kinds.Usages[i].Kind != expected {
+			t.Errorf("kinds %d - expected %q; got %q\n", i, expected, kinds.Usages[i].Kind)
+		}
+	}
+	// flags from AllFlagsState have no callsite to disagree with
+	detector.ObserveEvent(context.Background(), goldhook.EvaluationEvent{Key: "defaults", Kind: goldhook.AllFlagsKind})
+	if report := detector.Report(); len(report[0].Usages) != 2 {
+		t.Errorf("all flags - unexpected: %+v\n", report[0])
+	}
+}
+
+func TestMismatchDetectorTruncated(t *testing.T) {
+	callbacks := 0
+	const limit = 4
+	detector := goldhook.NewMismatchDetector(limit, func(context.Context, goldhook.Mismatch) {
+		callbacks++
+	})
+	// a default computed at runtime must not grow the detector without bound
+	for i := 0; i < limit+5; i++ {
+		detector.ObserveEvent(context.Background(), goldhook.EvaluationEvent{
+			Key:             "computed",
+			Kind:            goldhook.IntKind,
+			CallsiteDefault: ldvalue.Int(i),
+		})
+	}
+
+	if callbacks != limit-1 {
+		t.Errorf("callbacks - expected %d; got %d\n", limit-1, callbacks)
+	}
+	report := detector.Report()
+	if len(report) != 1 || len(report[0].Usages) != limit || !report[0].Truncated {
+		t.Errorf("report - expected %d truncated usages; got %+v\n", limit, report)
+	}
+}
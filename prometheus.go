@@ -0,0 +1,167 @@
+package goldhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// OverflowFlagKey is the flag label under which a PrometheusExporter counts
+// the evaluations of any flag keys beyond its cap.
+const OverflowFlagKey = "__overflow__"
+
+// PrometheusExporter is an Observer which is also an http.Handler, serving
+// evaluation counters and latency histograms in the Prometheus text
+// exposition format.
+type PrometheusExporter struct {
+	bounds   []time.Duration
+	maxFlags int
+
+	mu    sync.Mutex
+	flags map[string]*promFlag
+}
+
+type promFlag struct {
+	outcomes map[promOutcome]uint64
+	latency  Histogram
+}
+
+type promOutcome struct {
+	variation string
+	reason    ldreason.EvalReasonKind
+	errorKind ldreason.EvalErrorKind
+}
+
+// NewPrometheusExporter keeps series for at most maxFlags distinct flag keys
+// (or any number, if maxFlags is not positive), counting any others under
+// OverflowFlagKey. It uses the given latency histogram bounds (in any order,
+// with any duplicates dropped), or the DefaultLatencyBounds if there are none.
+func NewPrometheusExporter(maxFlags int, bounds ...time.Duration) *PrometheusExporter {
+	return &PrometheusExporter{
+		bounds:   latencyBounds(bounds),
+		maxFlags: maxFlags,
+		flags:    map[string]*promFlag{},
+	}
+}
+
+// Observe conforms to the Observer interface
+func (pe *PrometheusExporter) Observe(
+	_ context.Context,
+	key string,
+	_ lduser.User,
+	_ ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	outcome := promOutcome{reason: detail.Reason.GetKind()}
+	if idx, ok := detail.VariationIndex.Get(); ok {
+		outcome.variation = strconv.Itoa(idx)
+	}
+	if detail.Reason.GetKind() == ldreason.EvalReasonError || evalErr != nil {
+		outcome.errorKind = detail.Reason.GetErrorKind()
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pf, ok := pe.flags[key]
+	if !ok {
+		if pe.maxFlags > 0 && len(pe.flags) >= pe.maxFlags {
+			key = OverflowFlagKey
+			pf, ok = pe.flags[key]
+		}
+		if !ok {
+			pf = &promFlag{
+				outcomes: map[promOutcome]uint64{},
+				latency:  newHistogram(pe.bounds),
+			}
+			pe.flags[key] = pf
+		}
+	}
+	pf.outcomes[outcome]++
+	pf.latency.observe(elapsed)
+}
+
+// ServeHTTP conforms to the http.Handler interface
+func (pe *PrometheusExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	pe.WriteTo(w)
+}
+
+// WriteTo writes the current state of the metrics to w, in the Prometheus
+// text exposition format.
+func (pe *PrometheusExporter) WriteTo(w io.Writer) (int64, error) {
+	// render under the lock, but don't hold it while writing to the network
+	var buf bytes.Buffer
+	pe.render(&buf)
+	return buf.WriteTo(w)
+}
+
+func (pe *PrometheusExporter) render(buf *bytes.Buffer) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	keys := make([]string, 0, len(pe.flags))
+	for k := range pe.flags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(buf, "# HELP goldhook_evaluations_total Feature flag evaluations, by outcome.")
+	fmt.Fprintln(buf, "# TYPE goldhook_evaluations_total counter")
+	for _, k := range keys {
+		pf := pe.flags[k]
+		outcomes := make([]promOutcome, 0, len(pf.outcomes))
+		for o := range pf.outcomes {
+			outcomes = append(outcomes, o)
+		}
+		sort.Slice(outcomes, func(i, j int) bool {
+			a, b := outcomes[i], outcomes[j]
+			if a.variation != b.variation {
+				return a.variation < b.variation
+			}
+			if a.reason != b.reason {
+				return a.reason < b.reason
+			}
+			return a.errorKind < b.errorKind
+		})
+		for _, o := range outcomes {
+			fmt.Fprintf(buf, "goldhook_evaluations_total{flag=%s,variation=%s,reason=%s,error_kind=%s} %d\n",
+				promLabel(k), promLabel(o.variation), promLabel(string(o.reason)), promLabel(string(o.errorKind)),
+				pf.outcomes[o])
+		}
+	}
+
+	fmt.Fprintln(buf, "# HELP goldhook_evaluation_duration_seconds Time taken by feature flag evaluations.")
+	fmt.Fprintln(buf, "# TYPE goldhook_evaluation_duration_seconds histogram")
+	for _, k := range keys {
+		h := pe.flags[k].latency
+		var cumulative uint64
+		for i, bound := range h.Bounds {
+			cumulative += h.Counts[i]
+			fmt.Fprintf(buf, "goldhook_evaluation_duration_seconds_bucket{flag=%s,le=%s} %d\n",
+				promLabel(k), promLabel(strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)), cumulative)
+		}
+		cumulative += h.Counts[len(h.Bounds)]
+		fmt.Fprintf(buf, "goldhook_evaluation_duration_seconds_bucket{flag=%s,le=\"+Inf\"} %d\n", promLabel(k), cumulative)
+		fmt.Fprintf(buf, "goldhook_evaluation_duration_seconds_sum{flag=%s} %s\n",
+			promLabel(k), strconv.FormatFloat(h.Sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(buf, "goldhook_evaluation_duration_seconds_count{flag=%s} %d\n", promLabel(k), cumulative)
+	}
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabel(value string) string {
+	return `"` + promEscaper.Replace(value) + `"`
+}
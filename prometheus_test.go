@@ -0,0 +1,66 @@
+package goldhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+)
+
+func TestPrometheusExporter(t *testing.T) {
+	exporter := goldhook.NewPrometheusExporter(2, 10*time.Millisecond, time.Millisecond, 10*time.Millisecond)
+	user := lduser.NewUser("prometheus")
+	ctx := context.Background()
+
+	first := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthrough())
+	notReady := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorClientNotReady, ldvalue.Bool(false))
+
+	exporter.Observe(ctx, "alpha", user, ldvalue.Bool(false), 500*time.Microsecond, first, nil)
+	exporter.Observe(ctx, "alpha", user, ldvalue.Bool(false), 5*time.Millisecond, first, nil)
+	exporter.Observe(ctx, `be"ta`, user, ldvalue.Bool(false), time.Second, notReady, errors.New("not ready"))
+	// these are beyond the cap of two flag keys
+	exporter.Observe(ctx, "gamma", user, ldvalue.Bool(false), 0, first, nil)
+	exporter.Observe(ctx, "delta", user, ldvalue.Bool(false), 0, first, nil)
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type - unexpected: %q\n", ct)
+	}
+
+	expected := `# HELP goldhook_evaluations_total Feature flag evaluations, by outcome.
+# TYPE goldhook_evaluations_total counter
+goldhook_evaluations_total{flag="__overflow__",variation="0",reason="FALLTHROUGH",error_kind=""} 2
+goldhook_evaluations_total{flag="alpha",variation="0",reason="FALLTHROUGH",error_kind=""} 2
+goldhook_evaluations_total{flag="be\"ta",variation="",reason="ERROR",error_kind="CLIENT_NOT_READY"} 1
+# HELP goldhook_evaluation_duration_seconds Time taken by feature flag evaluations.
+# TYPE goldhook_evaluation_duration_seconds histogram
+goldhook_evaluation_duration_seconds_bucket{flag="__overflow__",le="0.001"} 2
+goldhook_evaluation_duration_seconds_bucket{flag="__overflow__",le="0.01"} 2
+goldhook_evaluation_duration_seconds_bucket{flag="__overflow__",le="+Inf"} 2
+goldhook_evaluation_duration_seconds_sum{flag="__overflow__"} 0
+goldhook_evaluation_duration_seconds_count{flag="__overflow__"} 2
+goldhook_evaluation_duration_seconds_bucket{flag="alpha",le="0.001"} 1
+goldhook_evaluation_duration_seconds_bucket{flag="alpha",le="0.01"} 2
+goldhook_evaluation_duration_seconds_bucket{flag="alpha",le="+Inf"} 2
+goldhook_evaluation_duration_seconds_sum{flag="alpha"} 0.0055
+goldhook_evaluation_duration_seconds_count{flag="alpha"} 2
+goldhook_evaluation_duration_seconds_bucket{flag="be\"ta",le="0.001"} 0
+goldhook_evaluation_duration_seconds_bucket{flag="be\"ta",le="0.01"} 0
+goldhook_evaluation_duration_seconds_bucket{flag="be\"ta",le="+Inf"} 1
+goldhook_evaluation_duration_seconds_sum{flag="be\"ta"} 1
+goldhook_evaluation_duration_seconds_count{flag="be\"ta"} 1
+`
+	if body := rec.Body.String(); body != expected {
+		t.Errorf("body - expected:\n%s\ngot:\n%s\n", expected, body)
+	}
+}
@@ -0,0 +1,150 @@
+package goldhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// Record is a single evaluation, as written by a Recorder and read by Replay
+type Record struct {
+	Time            time.Time                 `json:"time"`
+	Key             string                    `json:"key"`
+	User            lduser.User               `json:"user"`
+	CallsiteDefault ldvalue.Value             `json:"default"`
+	Elapsed         time.Duration             `json:"elapsed"`
+	Value           ldvalue.Value             `json:"value"`
+	VariationIndex  ldvalue.OptionalInt       `json:"variation"`
+	Reason          ldreason.EvaluationReason `json:"reason"`
+	Error           string                    `json:"error,omitempty"`
+}
+
+// RecordedError is the error handed to Observers by Replay, for an
+// evaluation which was recorded as having erred.
+type RecordedError string
+
+func (re RecordedError) Error() string {
+	return string(re)
+}
+
+// Recorder is an Observer which writes each evaluation to a JSON Lines
+// stream, one Record per line.
+type Recorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder writes to w, which it will close on Close if it is an io.Closer
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// OpenRecorder appends to the file at path, creating it if need be
+func OpenRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(f), nil
+}
+
+// Observe conforms to the Observer interface
+func (rec *Recorder) Observe(
+	_ context.Context,
+	key string,
+	user lduser.User,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	r := Record{
+		Time:            time.Now(),
+		Key:             key,
+		User:            user,
+		CallsiteDefault: callsiteDefault,
+		Elapsed:         elapsed,
+		Value:           detail.Value,
+		VariationIndex:  detail.VariationIndex,
+		Reason:          detail.Reason,
+	}
+	if evalErr != nil {
+		r.Error = evalErr.Error()
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if err := rec.enc.Encode(r); err != nil && rec.err == nil {
+		rec.err = fmt.Errorf("recording %q: %w", key, err)
+	}
+}
+
+// Err returns the first error encountered while writing, if any. Evaluations
+// which could not be written are otherwise skipped.
+func (rec *Recorder) Err() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.err
+}
+
+// Close closes the underlying writer, if it is an io.Closer
+func (rec *Recorder) Close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if c, ok := rec.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Replay reads Records from r, as written by a Recorder, and hands each of
+// them to the observers in turn. It stops at the end of r, or at the first
+// Record which can't be read, or when ctx is done, and returns the number of
+// Records replayed.
+func Replay(ctx context.Context, r io.Reader, observers ...Observer) (int, error) {
+	for _, o := range observers {
+		if o == nil {
+			return 0, fmt.Errorf("observers must not be nil")
+		}
+	}
+	dec := json.NewDecoder(r)
+	n := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		var rec Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, fmt.Errorf("record %d: %w", n+1, err)
+		}
+
+		detail := ldreason.EvaluationDetail{
+			Value:          rec.Value,
+			VariationIndex: rec.VariationIndex,
+			Reason:         rec.Reason,
+		}
+		var evalErr error
+		if rec.Error != "" {
+			evalErr = RecordedError(rec.Error)
+		}
+		for _, o := range observers {
+			o.Observe(ctx, rec.Key, rec.User, rec.CallsiteDefault, rec.Elapsed, detail, evalErr)
+		}
+		n++
+	}
+}
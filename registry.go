@@ -0,0 +1,237 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// FlagDeclaration is what a service declares about a flag it uses
+type FlagDeclaration struct {
+	Key         string
+	Owner       string
+	Description string
+
+	// Kind is the type of value expected, and Default the callsite default
+	// expected, if they are given
+	Kind    ValueKind
+	Default ldvalue.Value
+
+	// Created and Expires are when the flag was created and when it should be
+	// gone from the code, if they are given
+	Created time.Time
+	Expires time.Time
+
+	// Deprecated is why the flag should no longer be used, if it shouldn't
+	Deprecated string
+}
+
+// ViolationKind names a way in which an evaluation broke with the Registry
+type ViolationKind string
+
+const (
+	UndeclaredFlag ViolationKind = "undeclared"
+	WrongKind      ViolationKind = "wrong_kind"
+	WrongDefault   ViolationKind = "wrong_default"
+	ExpiredFlag    ViolationKind = "expired"
+	DeprecatedFlag ViolationKind = "deprecated"
+)
+
+// FlagViolation describes an evaluation which broke with the Registry
+type FlagViolation struct {
+	Kind    ViolationKind
+	Request EvaluationRequest
+
+	// Declaration is that of the flag evaluated, unless it was undeclared
+	Declaration FlagDeclaration
+
+	// Callsite is the file and line from which the evaluation was made, i.e.
+	// the first caller outside of this package
+	Callsite string
+}
+
+func (fv *FlagViolation) Error() string {
+	var detail string
+	switch fv.Kind {
+	case UndeclaredFlag:
+		detail = "is not declared"
+	case WrongKind:
+		detail = fmt.Sprintf("is declared as %s but was evaluated as %s", fv.Declaration.Kind, fv.Request.Kind)
+	case WrongDefault:
+		detail = fmt.Sprintf("is declared with default %s but was evaluated with %s",
+			fv.Declaration.Default.JSONString(), fv.Request.CallsiteDefault.JSONString())
+	case ExpiredFlag:
+		detail = fmt.Sprintf("expired on %s", fv.Declaration.Expires.Format("2006-01-02"))
+	case DeprecatedFlag:
+		detail = fmt.Sprintf("is deprecated: %s", fv.Declaration.Deprecated)
+	}
+	return fmt.Sprintf("flag %q %s, at %s", fv.Request.Key, detail, fv.Callsite)
+}
+
+// ViolationHandler is notified of evaluations which break with a Registry
+type ViolationHandler func(ctx context.Context, v *FlagViolation)
+
+func logViolation(_ context.Context, v *FlagViolation) {
+	log.Printf("goldhook: %v", v)
+}
+
+// Registry holds the declarations of the flags a service uses, so that an
+// ObservedEvaluator can check each evaluation against them.
+type Registry struct {
+	onViolation ViolationHandler
+
+	mu       sync.RWMutex
+	flags    map[string]FlagDeclaration
+	reported map[violationSite]bool
+}
+
+// violationSite identifies a violation which has already been reported
+type violationSite struct {
+	kind     ViolationKind
+	key      string
+	callsite string
+}
+
+// NewRegistry reports violations to the given handler, or to the standard
+// logger if it is nil. Each violation is reported the first time it is seen
+// from a given callsite, rather than on every evaluation. The handler is run
+// synchronously; a panic in it is handed to the PanicHandler of the
+// ObservedEvaluator, as for its observers.
+func NewRegistry(onViolation ViolationHandler, decls ...FlagDeclaration) (*Registry, error) {
+	if onViolation == nil {
+		onViolation = logViolation
+	}
+	r := &Registry{
+		onViolation: onViolation,
+		flags:       map[string]FlagDeclaration{},
+		reported:    map[violationSite]bool{},
+	}
+	if err := r.Declare(decls...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Declare adds the given declarations to the Registry. Each flag key may
+// only be declared once.
+func (r *Registry) Declare(decls ...FlagDeclaration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batch := map[string]bool{}
+	for i, d := range decls {
+		if d.Key == "" {
+			return fmt.Errorf("declaration %d has no key", i)
+		}
+		if _, ok := r.flags[d.Key]; ok || batch[d.Key] {
+			return fmt.Errorf("flag %q is already declared", d.Key)
+		}
+		batch[d.Key] = true
+	}
+	for _, d := range decls {
+		r.flags[d.Key] = d
+	}
+	return nil
+}
+
+// Lookup returns the declaration of the flag key, if there is one
+func (r *Registry) Lookup(key string) (FlagDeclaration, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.flags[key]
+	return d, ok
+}
+
+// Declarations returns every declaration, ordered by key
+func (r *Registry) Declarations() []FlagDeclaration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	decls := make([]FlagDeclaration, 0, len(r.flags))
+	for _, d := range r.flags {
+		decls = append(decls, d)
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		return decls[i].Key < decls[j].Key
+	})
+	return decls
+}
+
+// check reports each way in which the request breaks with the Registry, which
+// has not already been reported for the same callsite
+func (r *Registry) check(ctx context.Context, req EvaluationRequest, now time.Time) {
+	d, ok := r.Lookup(req.Key)
+	kinds := []ViolationKind{}
+	// AllFlagsState covers every flag in the environment, not only those
+	// this service declares, and has no callsite kind or default to check
+	bulk := req.Kind == AllFlagsKind
+	switch {
+	case !ok:
+		if !bulk {
+			kinds = append(kinds, UndeclaredFlag)
+		}
+	default:
+		if d.Kind != "" && d.Kind != req.Kind && !bulk {
+			kinds = append(kinds, WrongKind)
+		}
+		if !d.Default.IsNull() && !d.Default.Equal(req.CallsiteDefault) && !bulk {
+			kinds = append(kinds, WrongDefault)
+		}
+		if !d.Expires.IsZero() && now.After(d.Expires) {
+			kinds = append(kinds, ExpiredFlag)
+		}
+		if d.Deprecated != "" {
+			kinds = append(kinds, DeprecatedFlag)
+		}
+	}
+	if len(kinds) == 0 {
+		return
+	}
+	site := callsite()
+	for _, k := range kinds {
+		if !r.firstReport(violationSite{kind: k, key: req.Key, callsite: site}) {
+			continue
+		}
+		r.onViolation(ctx, &FlagViolation{
+			Kind:        k,
+			Request:     req,
+			Declaration: d,
+			Callsite:    site,
+		})
+	}
+}
+
+// firstReport notes the violation, and reports whether it is the first time
+func (r *Registry) firstReport(vs violationSite) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reported[vs] {
+		return false
+	}
+	r.reported[vs] = true
+	return true
+}
+
+var packagePath = reflect.TypeOf((*Registry)(nil)).Elem().PkgPath()
+
+// callsite finds the first caller outside of this package
+func callsite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePath+".") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// Divergence describes an evaluation on which the primary and secondary
+// Evaluators of a ShadowEvaluator disagreed, as to either the value or the
+// variation index.
+type Divergence struct {
+	Request   EvaluationRequest
+	Primary   EvaluationResult
+	Secondary EvaluationResult
+}
+
+// DivergenceHandler is notified of every Divergence found by a ShadowEvaluator
+type DivergenceHandler func(ctx context.Context, d Divergence)
+
+// ShadowEvaluator serves every evaluation from a primary Evaluator, and makes
+// the same evaluation of a secondary Evaluator in the background, so as to
+// compare the two, e.g. while migrating between environments or backends.
+type ShadowEvaluator struct {
+	primary      Evaluator
+	secondary    Evaluator
+	onDivergence DivergenceHandler
+	rate         float64
+	shadows      *shadows
+	onPanic      PanicHandler
+	ctx          context.Context
+}
+
+// shadows is shared by every copy of a ShadowEvaluator
+type shadows struct {
+	compared uint64
+	skipped  uint64
+	diverged uint64
+
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	// mu guards closed, so that no secondary evaluation is added to wg once
+	// Wait has begun
+	mu     sync.Mutex
+	closed bool
+}
+
+// reserve takes a slot for a secondary evaluation, unless they have all been
+// taken, or Wait has been called
+func (s *shadows) reserve() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+// NewShadowEvaluator compares the given fraction of evaluations (chosen by
+// hashing the user key, as per Sample), running at most limit secondary
+// evaluations at once. Evaluations beyond the limit are skipped rather than
+// waited for, so the secondary can never hold up the primary.
+func NewShadowEvaluator(
+	ctx context.Context,
+	primary, secondary Evaluator,
+	onDivergence DivergenceHandler,
+	rate float64,
+	limit int,
+) (*ShadowEvaluator, error) {
+	if primary == nil || secondary == nil {
+		return nil, fmt.Errorf("evaluators must not be nil")
+	}
+	if onDivergence == nil {
+		return nil, fmt.Errorf("divergence handler must not be nil")
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	return &ShadowEvaluator{
+		primary:      primary,
+		secondary:    secondary,
+		onDivergence: onDivergence,
+		rate:         rate,
+		shadows:      &shadows{slots: make(chan struct{}, limit)},
+		ctx:          orBackground(ctx),
+	}, nil
+}
+
+func (se *ShadowEvaluator) WithContext(c context.Context) Evaluator {
+	cp := *se
+	cp.ctx = orBackground(c)
+	return &cp
+}
+
+// WithPanicHandler returns a copy of the ShadowEvaluator, which hands any
+// panic of a secondary evaluation (or of the DivergenceHandler) to fn, rather
+// than logging it. The secondary is the Observer of the ObserverPanic, with an
+// Index of -1.
+func (se *ShadowEvaluator) WithPanicHandler(fn PanicHandler) *ShadowEvaluator {
+	cp := *se
+	cp.onPanic = fn
+	return &cp
+}
+
+// Compared is the number of evaluations made of the secondary
+func (se *ShadowEvaluator) Compared() uint64 {
+	return atomic.LoadUint64(&se.shadows.compared)
+}
+
+// Skipped is the number of sampled evaluations not made of the secondary,
+// because the limit was reached, or Wait had been called
+func (se *ShadowEvaluator) Skipped() uint64 {
+	return atomic.LoadUint64(&se.shadows.skipped)
+}
+
+// Diverged is the number of evaluations on which the secondary disagreed
+func (se *ShadowEvaluator) Diverged() uint64 {
+	return atomic.LoadUint64(&se.shadows.diverged)
+}
+
+// Wait waits for the secondary evaluations in progress to finish, e.g. before
+// shutting down. If ctx is done first, Wait returns its error. Once Wait has
+// been called, no more secondary evaluations are started (by any copy of the
+// ShadowEvaluator), though the primary continues to be served.
+func (se *ShadowEvaluator) Wait(ctx context.Context) error {
+	se.shadows.mu.Lock()
+	se.shadows.closed = true
+	se.shadows.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		se.shadows.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (se *ShadowEvaluator) evaluate(req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+	start := time.Now()
+	detail, err := variation(bind(se.ctx, se.primary), req)
+	if sampled(req.User.GetKey(), se.rate) {
+		se.shadow(req, EvaluationResult{
+			Start:   start,
+			Elapsed: time.Since(start),
+			Detail:  detail,
+			Err:     err,
+		})
+	}
+	return detail, err
+}
+
+// shadow evaluates the secondary in the background, if there is a slot free
+func (se *ShadowEvaluator) shadow(req EvaluationRequest, primary EvaluationResult) {
+	if !se.shadows.reserve() {
+		atomic.AddUint64(&se.shadows.skipped, 1)
+		return
+	}
+	// the secondary outlives the evaluation, so mustn't be cancelled with it
+	ctx := detached{se.ctx}
+	go func() {
+		defer se.shadows.wg.Done()
+		defer func() { <-se.shadows.slots }()
+		defer func() {
+			if r := recover(); r != nil {
+				handlePanic(se.onPanic, &ObserverPanic{
+					Observer: se.secondary,
+					Index:    -1,
+					Key:      req.Key,
+					Value:    r,
+					Stack:    debug.Stack(),
+				})
+			}
+		}()
+
+		start := time.Now()
+		detail, err := variation(bind(ctx, se.secondary), req)
+		secondary := EvaluationResult{
+			Start:   start,
+			Elapsed: time.Since(start),
+			Detail:  detail,
+			Err:     err,
+		}
+		atomic.AddUint64(&se.shadows.compared, 1)
+		if primary.Detail.Value.Equal(secondary.Detail.Value) &&
+			primary.Detail.VariationIndex == secondary.Detail.VariationIndex {
+			return
+		}
+		atomic.AddUint64(&se.shadows.diverged, 1)
+		se.onDivergence(ctx, Divergence{
+			Request:   req,
+			Primary:   primary,
+			Secondary: secondary,
+		})
+	}()
+}
+
+func (se *ShadowEvaluator) BoolVariation(key string, user lduser.User, defaultVal bool) (bool, error) {
+	_, detail, err := se.BoolVariationDetail(key, user, defaultVal)
+	return detail.Value.BoolValue(), err
+}
+
+func (se *ShadowEvaluator) BoolVariationDetail(key string, user lduser.User, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.Bool(defaultVal),
+		Kind:            BoolKind,
+	})
+	return detail.Value.BoolValue(), detail, err
+}
+
+func (se *ShadowEvaluator) Float64Variation(key string, user lduser.User, defaultVal float64) (float64, error) {
+	_, detail, err := se.Float64VariationDetail(key, user, defaultVal)
+	return detail.Value.Float64Value(), err
+}
+
+func (se *ShadowEvaluator) Float64VariationDetail(key string, user lduser.User, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.Float64(defaultVal),
+		Kind:            Float64Kind,
+	})
+	return detail.Value.Float64Value(), detail, err
+}
+
+func (se *ShadowEvaluator) IntVariation(key string, user lduser.User, defaultVal int) (int, error) {
+	_, detail, err := se.IntVariationDetail(key, user, defaultVal)
+	return detail.Value.IntValue(), err
+}
+
+func (se *ShadowEvaluator) IntVariationDetail(key string, user lduser.User, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.Int(defaultVal),
+		Kind:            IntKind,
+	})
+	return detail.Value.IntValue(), detail, err
+}
+
+func (se *ShadowEvaluator) JSONVariation(key string, user lduser.User, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	_, detail, err := se.JSONVariationDetail(key, user, defaultVal)
+	return detail.Value, err
+}
+
+func (se *ShadowEvaluator) JSONVariationDetail(key string, user lduser.User, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: defaultVal,
+		Kind:            JSONKind,
+	})
+	return detail.Value, detail, err
+}
+
+func (se *ShadowEvaluator) StringVariation(key string, user lduser.User, defaultVal string) (string, error) {
+	_, detail, err := se.StringVariationDetail(key, user, defaultVal)
+	return detail.Value.StringValue(), err
+}
+
+func (se *ShadowEvaluator) StringVariationDetail(key string, user lduser.User, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.String(defaultVal),
+		Kind:            StringKind,
+	})
+	return detail.Value.StringValue(), detail, err
+}
@@ -0,0 +1,250 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/goldhooktest"
+)
+
+func TestShadowEvaluator(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	primary := goldhooktest.NewFake().
+		Set("agree", goldhooktest.Serve(ldvalue.Bool(true), 0)).
+		Set("value", goldhooktest.Serve(ldvalue.String("blue"), 1)).
+		Set("variation", goldhooktest.Serve(ldvalue.Int(3), 1))
+	secondary := goldhooktest.NewFake().
+		Set("agree", goldhooktest.Serve(ldvalue.Bool(true), 0).WithLatency(time.Millisecond)).
+		Set("value", goldhooktest.Serve(ldvalue.String("green"), 1)).
+		Set("variation", goldhooktest.Serve(ldvalue.Int(3), 2))
+
+	if _, err := goldhook.NewShadowEvaluator(context.Background(), primary, nil, func(context.Context, goldhook.Divergence) {}, 1, 1); err == nil {
+		t.Errorf("expected an error for a nil secondary\n")
+	}
+	if _, err := goldhook.NewShadowEvaluator(context.Background(), primary, secondary, nil, 1, 1); err == nil {
+		t.Errorf("expected an error for a nil handler\n")
+	}
+	if _, err := goldhook.NewShadowEvaluator(context.Background(), primary, secondary, func(context.Context, goldhook.Divergence) {}, 1, 0); err == nil {
+		t.Errorf("expected an error for no limit\n")
+	}
+
+	var mu sync.Mutex
+	divergences := map[string]goldhook.Divergence{}
+	shadow, err := goldhook.NewShadowEvaluator(context.Background(), primary, secondary,
+		func(_ context.Context, d goldhook.Divergence) {
+			mu.Lock()
+			defer mu.Unlock()
+			divergences[d.Request.Key] = d
+		},
+		1, 10,
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var _ goldhook.ContextualEvaluator = shadow
+
+	user := lduser.NewUser(now)
+	if v, _ := shadow.BoolVariation("agree", user, false); !v {
+		t.Errorf("expected %t; got %t\n", true, v)
+	}
+	// the primary is always served
+	if v, _ := shadow.StringVariation("value", user, ""); v != "blue" {
+		t.Errorf("expected %q; got %q\n", "blue", v)
+	}
+	if v, _ := shadow.IntVariation("variation", user, 0); v != 3 {
+		t.Errorf("expected %d; got %d\n", 3, v)
+	}
+	if err := shadow.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	if shadow.Compared() != 3 || shadow.Diverged() != 2 || shadow.Skipped() != 0 {
+		t.Errorf("unexpected counts: %d %d %d\n", shadow.Compared(), shadow.Diverged(), shadow.Skipped())
+	}
+	if _, ok := divergences["agree"]; ok {
+		t.Errorf("unexpected divergence: %+v\n", divergences["agree"])
+	}
+	d := divergences["value"]
+	if d.Primary.Detail.Value.StringValue() != "blue" || d.Secondary.Detail.Value.StringValue() != "green" {
+		t.Errorf("unexpected: %+v\n", d)
+	}
+	if d.Request.User.GetKey() != now || d.Request.Kind != goldhook.StringKind || d.Request.CallsiteDefault.StringValue() != "" {
+		t.Errorf("unexpected request: %+v\n", d.Request)
+	}
+	d = divergences["variation"]
+	if d.Primary.Detail.VariationIndex.IntValue() != 1 || d.Secondary.Detail.VariationIndex.IntValue() != 2 {
+		t.Errorf("unexpected: %+v\n", d)
+	}
+	if d.Primary.Start.IsZero() || d.Secondary.Start.IsZero() {
+		t.Errorf("expected timings; got %+v\n", d)
+	}
+
+	// nothing is sampled at a rate of 0
+	unsampled, err := goldhook.NewShadowEvaluator(context.Background(), primary, secondary,
+		func(context.Context, goldhook.Divergence) {}, 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	unsampled.StringVariation("value", user, "")
+	if unsampled.Compared() != 0 || unsampled.Skipped() != 0 {
+		t.Errorf("unexpected counts: %d %d\n", unsampled.Compared(), unsampled.Skipped())
+	}
+}
+
+func TestShadowEvaluatorWrapped(t *testing.T) {
+	primary := goldhooktest.NewFake().Set("value", goldhooktest.Serve(ldvalue.String("blue"), 1))
+	secondary := goldhooktest.NewFake().Set("value", goldhooktest.Serve(ldvalue.String("green"), 1))
+
+	seen := make(chan interface{}, 1)
+	shadow, err := goldhook.NewShadowEvaluator(context.Background(), primary, secondary,
+		func(ctx context.Context, _ goldhook.Divergence) {
+			seen <- ctx.Value(ctxKey("request"))
+		},
+		1, 1,
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	// the shadow sits beneath two ObservedEvaluators
+	inner, err := goldhook.NewEvaluator(context.Background(), shadow)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err := goldhook.NewEvaluator(context.Background(), inner)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// the context of the evaluation reaches the DivergenceHandler
+	ctx := context.WithValue(context.Background(), ctxKey("request"), "wrapped")
+	user := lduser.NewUser("wrapped")
+	if v, _ := hooked.WithContext(ctx).StringVariation("value", user, ""); v != "blue" {
+		t.Errorf("expected %q; got %q\n", "blue", v)
+	}
+	if err := shadow.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	select {
+	case v := <-seen:
+		if v != "wrapped" {
+			t.Errorf("expected %q; got %v\n", "wrapped", v)
+		}
+	default:
+		t.Errorf("expected a divergence\n")
+	}
+}
+
+// gatedEvaluator holds up every bool evaluation until its gate is closed
+type gatedEvaluator struct {
+	goldhook.Evaluator
+	gate chan struct{}
+}
+
+func (ge gatedEvaluator) BoolVariationDetail(key string, user lduser.User, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	<-ge.gate
+	return ge.Evaluator.BoolVariationDetail(key, user, defaultVal)
+}
+
+func TestShadowEvaluatorLimit(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	primary := goldhooktest.NewFake().
+		Set("slow", goldhooktest.Serve(ldvalue.Bool(true), 0))
+	secondary := gatedEvaluator{
+		Evaluator: goldhooktest.NewFake().Set("slow", goldhooktest.Serve(ldvalue.Bool(false), 1)),
+		gate:      make(chan struct{}),
+	}
+
+	shadow, err := goldhook.NewShadowEvaluator(context.Background(), primary, secondary,
+		func(context.Context, goldhook.Divergence) {}, 1, 2)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUser(now)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		shadow.BoolVariation("slow", user, false)
+	}
+	// the secondary doesn't hold up the primary
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the primary to be served promptly; took %v\n", elapsed)
+	}
+	if shadow.Skipped() != 3 {
+		t.Errorf("expected %d skipped; got %d\n", 3, shadow.Skipped())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := shadow.Wait(ctx); err == nil {
+		t.Errorf("expected an error while the secondary is blocked\n")
+	}
+	close(secondary.gate)
+	if err := shadow.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if shadow.Compared() != 2 || shadow.Diverged() != 2 {
+		t.Errorf("unexpected counts: %d %d\n", shadow.Compared(), shadow.Diverged())
+	}
+}
+
+// panickingEvaluator panics on every bool evaluation
+type panickingEvaluator struct {
+	goldhook.Evaluator
+}
+
+func (pe panickingEvaluator) BoolVariationDetail(key string, user lduser.User, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	panic(key)
+}
+
+func TestShadowEvaluatorPanics(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	primary := goldhooktest.NewFake().
+		Set("flaky", goldhooktest.Serve(ldvalue.Bool(true), 0))
+	secondary := panickingEvaluator{goldhooktest.NewFake()}
+
+	var mu sync.Mutex
+	panics := []*goldhook.ObserverPanic{}
+	shadow, err := goldhook.NewShadowEvaluator(context.Background(), primary, secondary,
+		func(context.Context, goldhook.Divergence) {}, 1, 10)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	shadow = shadow.WithPanicHandler(func(p *goldhook.ObserverPanic) {
+		mu.Lock()
+		defer mu.Unlock()
+		panics = append(panics, p)
+	})
+
+	user := lduser.NewUser(now)
+	if v, _ := shadow.BoolVariation("flaky", user, false); !v {
+		t.Errorf("expected %t; got %t\n", true, v)
+	}
+	if err := shadow.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if len(panics) != 1 || panics[0].Key != "flaky" || panics[0].Index != -1 || panics[0].Observer != secondary || len(panics[0].Stack) == 0 {
+		t.Errorf("unexpected panics: %+v\n", panics)
+	}
+
+	// no secondary evaluation is started once Wait has been called
+	if v, _ := shadow.BoolVariation("flaky", user, false); !v {
+		t.Errorf("expected %t; got %t\n", true, v)
+	}
+	if err := shadow.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if len(panics) != 1 || shadow.Skipped() != 1 {
+		t.Errorf("unexpected: %d panics, %d skipped\n", len(panics), shadow.Skipped())
+	}
+}
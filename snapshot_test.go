@@ -0,0 +1,109 @@
+package goldhook_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flags.json")
+
+	snapshot, err := goldhook.OpenSnapshot(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, ok := snapshot.Lookup("theme"); ok {
+		t.Errorf("expected no value in a new snapshot\n")
+	}
+	snapshot.Record("theme", ldvalue.String("dark"))
+	snapshot.Record("limit", ldvalue.Int(10))
+
+	// refreshed in the background
+	var values map[string]interface{}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &values) == nil {
+			break
+		}
+	}
+	if values["theme"] != "dark" || values["limit"] != float64(10) {
+		t.Errorf("unexpected: %v\n", values)
+	}
+
+	snapshot.Record("motd", ldvalue.String("hello"))
+	if err := snapshot.Close(); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if err := snapshot.Err(); err != nil {
+		t.Errorf("unexpected: %v\n", err)
+	}
+
+	// and read back again
+	reopened, err := goldhook.OpenSnapshot(path, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer reopened.Close()
+	if v, ok := reopened.Lookup("theme"); !ok || v.StringValue() != "dark" {
+		t.Errorf("unexpected: %v %t\n", v, ok)
+	}
+	if v, ok := reopened.Lookup("motd"); !ok || v.StringValue() != "hello" {
+		t.Errorf("unexpected: %v %t\n", v, ok)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected no temporary files; got %v\n", entries)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, err := goldhook.OpenSnapshot(path, 0); err == nil {
+		t.Errorf("expected an error for a malformed file\n")
+	}
+}
+
+func TestSnapshotVaried(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	if err := os.WriteFile(path, []byte(`{"limit": 10, "theme": "dark"}`), 0644); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	snapshot, err := goldhook.OpenSnapshot(path, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	snapshot.Record("theme", ldvalue.String("dark"))
+	snapshot.Record("theme", ldvalue.String("dark"))
+	snapshot.Record("limit", ldvalue.Int(20))
+	if v, ok := snapshot.Lookup("limit"); !ok || v.IntValue() != 20 {
+		t.Errorf("unexpected: %v %t\n", v, ok)
+	}
+
+	// a second value since opening drops the flag, for good
+	snapshot.Record("limit", ldvalue.Int(30))
+	snapshot.Record("limit", ldvalue.Int(20))
+	if v, ok := snapshot.Lookup("limit"); ok {
+		t.Errorf("expected limit to be dropped; got %v\n", v)
+	}
+	if v, ok := snapshot.Lookup("theme"); !ok || v.StringValue() != "dark" {
+		t.Errorf("unexpected: %v %t\n", v, ok)
+	}
+	if err := snapshot.Close(); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	var values map[string]interface{}
+	if data, err := os.ReadFile(path); err != nil || json.Unmarshal(data, &values) != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, ok := values["limit"]; ok || values["theme"] != "dark" {
+		t.Errorf("unexpected: %v\n", values)
+	}
+}
@@ -0,0 +1,82 @@
+package goldhook
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// ValueKind names the type of value that was requested at the callsite,
+// i.e. which of the *Variation methods was invoked.
+type ValueKind string
+
+const (
+	BoolKind    ValueKind = "bool"
+	Float64Kind ValueKind = "float64"
+	IntKind     ValueKind = "int"
+	JSONKind    ValueKind = "json"
+	StringKind  ValueKind = "string"
+
+	// AllFlagsKind marks a flag included in the result of AllFlagsState,
+	// rather than one requested from a *Variation method
+	AllFlagsKind ValueKind = "all_flags"
+)
+
+// EvaluationRequest describes a single flag evaluation, as requested at the callsite.
+type EvaluationRequest struct {
+	Key             string
+	User            lduser.User
+	CallsiteDefault ldvalue.Value
+	Kind            ValueKind
+}
+
+// EvaluationResult describes the outcome of a single flag evaluation.
+type EvaluationResult struct {
+	Start   time.Time
+	Elapsed time.Duration
+	Detail  ldreason.EvaluationDetail
+	Err     error
+
+	// InterceptedBy names the Interceptor which changed the result, if any
+	InterceptedBy string
+
+	// Extensions holds whatever was attached to the evaluation via SetExtension
+	Extensions map[string]interface{}
+}
+
+// SeriesData is whatever a StagedObserver wants to carry from the
+// BeforeEvaluation stage to the AfterEvaluation stage of the same evaluation.
+type SeriesData map[string]interface{}
+
+// StagedObserver is interested in a feature flag evaluation both before and
+// after it happens, e.g. to open a span or start a timer.
+type StagedObserver interface {
+	// BeforeEvaluation is invoked before the underlying client is called. The
+	// returned SeriesData is handed to AfterEvaluation for the same evaluation.
+	BeforeEvaluation(ctx context.Context, req EvaluationRequest) SeriesData
+
+	// AfterEvaluation is invoked after the underlying client has returned.
+	AfterEvaluation(ctx context.Context, req EvaluationRequest, data SeriesData, result EvaluationResult)
+}
+
+// AsStaged adapts an Observer into a StagedObserver, which does nothing before
+// the evaluation and invokes Observe after it.
+func AsStaged(o Observer) StagedObserver {
+	return observerStages{o}
+}
+
+type observerStages struct {
+	Observer
+}
+
+func (os observerStages) BeforeEvaluation(context.Context, EvaluationRequest) SeriesData {
+	return nil
+}
+
+func (os observerStages) AfterEvaluation(ctx context.Context, req EvaluationRequest, _ SeriesData, result EvaluationResult) {
+	ctx = withInterceptedBy(ctx, result.InterceptedBy)
+	os.Observe(ctx, req.Key, req.User, req.CallsiteDefault, result.Elapsed, result.Detail, result.Err)
+}
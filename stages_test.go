@@ -0,0 +1,85 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+	ld "gopkg.in/launchdarkly/go-server-sdk.v5"
+
+	"github.com/nelz9999/goldhook"
+)
+
+type recordingStages struct {
+	before []goldhook.EvaluationRequest
+	after  []goldhook.EvaluationRequest
+	data   []goldhook.SeriesData
+}
+
+func (rs *recordingStages) BeforeEvaluation(_ context.Context, req goldhook.EvaluationRequest) goldhook.SeriesData {
+	rs.before = append(rs.before, req)
+	return goldhook.SeriesData{"key": req.Key}
+}
+
+func (rs *recordingStages) AfterEvaluation(_ context.Context, req goldhook.EvaluationRequest, data goldhook.SeriesData, _ goldhook.EvaluationResult) {
+	rs.after = append(rs.after, req)
+	rs.data = append(rs.data, data)
+}
+
+func TestStagedObserver(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	stages := &recordingStages{}
+	hooked, err := goldhook.NewStagedEvaluator(context.Background(), client, stages)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewAnonymousUser(now)
+	hooked.BoolVariation("bool-"+now, user, true)
+	hooked.Float64Variation("float-"+now, user, 1.5)
+	hooked.IntVariation("int-"+now, user, 42)
+	hooked.JSONVariation("json-"+now, user, ldvalue.ArrayOf())
+	hooked.StringVariation("string-"+now, user, now)
+
+	kinds := []goldhook.ValueKind{
+		goldhook.BoolKind,
+		goldhook.Float64Kind,
+		goldhook.IntKind,
+		goldhook.JSONKind,
+		goldhook.StringKind,
+	}
+	if len(stages.before) != len(kinds) || len(stages.after) != len(kinds) {
+		t.Fatalf("stages - expected %d; got %d before, %d after\n", len(kinds), len(stages.before), len(stages.after))
+	}
+	for i, kind := range kinds {
+		if stages.before[i].Kind != kind {
+			t.Errorf("kind - expected %q; got %q\n", kind, stages.before[i].Kind)
+		}
+		if stages.before[i].Key != stages.after[i].Key {
+			t.Errorf("request - expected %q; got %q\n", stages.before[i].Key, stages.after[i].Key)
+		}
+		// the data returned from BeforeEvaluation is handed to AfterEvaluation
+		if stages.data[i]["key"] != stages.after[i].Key {
+			t.Errorf("data - expected %q; got %v\n", stages.after[i].Key, stages.data[i]["key"])
+		}
+	}
+}
+
+func TestStagedNil(t *testing.T) {
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, err := goldhook.NewStagedEvaluator(context.Background(), client, nil); err == nil {
+		t.Errorf("expected error for nil stage\n")
+	}
+}
@@ -0,0 +1,179 @@
+package goldhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// StaleFlag describes a flag which has only been serving one outcome, and so
+// is a candidate to be cleaned out of the code.
+type StaleFlag struct {
+	Key       string
+	FirstSeen time.Time
+	LastSeen  time.Time
+
+	// Variations and Reasons are those seen within the window. Evaluations
+	// which served the callsite default have a variation index of -1.
+	Variations []int
+	Reasons    []ldreason.EvalReasonKind
+
+	// SingleVariation is true if only one variation has been served, and
+	// Untargeted is true if only FALLTHROUGH or OFF have been seen as reasons,
+	// for at least the threshold. Since is when that started.
+	SingleVariation bool
+	Untargeted      bool
+	Since           time.Time
+}
+
+// StaleFlagDetector tracks the variations served and reasons seen per flag
+// key, over a rolling window, so as to report on flags which have only been
+// serving one outcome for longer than a threshold.
+type StaleFlagDetector struct {
+	window    time.Duration
+	threshold time.Duration
+
+	mu    sync.Mutex
+	flags map[string]*flagHistory
+}
+
+type flagHistory struct {
+	firstSeen  time.Time
+	lastSeen   time.Time
+	variations map[int]time.Time
+	reasons    map[ldreason.EvalReasonKind]time.Time
+
+	// the latest a variation (or targeted reason) was seen, amongst those
+	// which have since dropped out of the window
+	prunedVariation time.Time
+	prunedReason    time.Time
+}
+
+// NewStaleFlagDetector forgets outcomes not seen within window, and reports
+// flags which have served a single outcome for at least threshold. A flag
+// not evaluated at all within window is forgotten entirely.
+func NewStaleFlagDetector(window, threshold time.Duration) *StaleFlagDetector {
+	return &StaleFlagDetector{
+		window:    window,
+		threshold: threshold,
+		flags:     map[string]*flagHistory{},
+	}
+}
+
+// Observe conforms to the Observer interface
+func (sd *StaleFlagDetector) Observe(
+	_ context.Context,
+	key string,
+	_ lduser.User,
+	_ ldvalue.Value,
+	_ time.Duration,
+	detail ldreason.EvaluationDetail,
+	_ error,
+) {
+	now := time.Now()
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	fh, ok := sd.flags[key]
+	if !ok {
+		fh = &flagHistory{
+			firstSeen:  now,
+			variations: map[int]time.Time{},
+			reasons:    map[ldreason.EvalReasonKind]time.Time{},
+		}
+		sd.flags[key] = fh
+	}
+	// record before pruning, so that an outcome seen again is not mistaken
+	// for one which has dropped out of the window
+	fh.lastSeen = now
+	fh.variations[detail.VariationIndex.OrElse(-1)] = now
+	fh.reasons[detail.Reason.GetKind()] = now
+	fh.prune(now.Add(-sd.window))
+}
+
+// Report lists the flags which are currently stale, ordered by key
+func (sd *StaleFlagDetector) Report() []StaleFlag {
+	now := time.Now()
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	report := []StaleFlag{}
+	for key, fh := range sd.flags {
+		fh.prune(now.Add(-sd.window))
+		if len(fh.variations) == 0 {
+			delete(sd.flags, key)
+			continue
+		}
+
+		sf := StaleFlag{
+			Key:       key,
+			FirstSeen: fh.firstSeen,
+			LastSeen:  fh.lastSeen,
+		}
+		for v := range fh.variations {
+			sf.Variations = append(sf.Variations, v)
+		}
+		sort.Ints(sf.Variations)
+		untargeted := true
+		for r := range fh.reasons {
+			sf.Reasons = append(sf.Reasons, r)
+			untargeted = untargeted && !targeted(r)
+		}
+		sort.Slice(sf.Reasons, func(i, j int) bool {
+			return sf.Reasons[i] < sf.Reasons[j]
+		})
+
+		if since := latest(fh.firstSeen, fh.prunedVariation); len(fh.variations) == 1 && now.Sub(since) >= sd.threshold {
+			sf.SingleVariation = true
+			sf.Since = since
+		}
+		if since := latest(fh.firstSeen, fh.prunedReason); untargeted && now.Sub(since) >= sd.threshold {
+			sf.Untargeted = true
+			if sf.Since.IsZero() || since.Before(sf.Since) {
+				sf.Since = since
+			}
+		}
+		if sf.SingleVariation || sf.Untargeted {
+			report = append(report, sf)
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Key < report[j].Key
+	})
+	return report
+}
+
+func (fh *flagHistory) prune(cutoff time.Time) {
+	for v, seen := range fh.variations {
+		if seen.Before(cutoff) {
+			delete(fh.variations, v)
+			fh.prunedVariation = latest(fh.prunedVariation, seen)
+		}
+	}
+	for r, seen := range fh.reasons {
+		if seen.Before(cutoff) {
+			delete(fh.reasons, r)
+			if targeted(r) {
+				fh.prunedReason = latest(fh.prunedReason, seen)
+			}
+		}
+	}
+}
+
+// targeted is true for reasons other than those a flag gives when it is
+// serving the same thing to everyone
+func targeted(r ldreason.EvalReasonKind) bool {
+	return r != ldreason.EvalReasonFallthrough && r != ldreason.EvalReasonOff
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
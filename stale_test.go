@@ -0,0 +1,109 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+)
+
+func TestStaleFlagDetector(t *testing.T) {
+	detector := goldhook.NewStaleFlagDetector(time.Hour, 30*time.Millisecond)
+	ctx := context.Background()
+	user := lduser.NewUser("stale")
+
+	detail := func(variation int, reason ldreason.EvaluationReason) ldreason.EvaluationDetail {
+		return ldreason.NewEvaluationDetail(ldvalue.Int(variation), variation, reason)
+	}
+	fallthrough_ := ldreason.NewEvalReasonFallthrough()
+	target := ldreason.NewEvalReasonTargetMatch()
+	rule := ldreason.NewEvalReasonRuleMatch(0, "rule")
+
+	observe := func() {
+		detector.Observe(ctx, "single", user, ldvalue.Int(0), 0, detail(0, fallthrough_), nil)
+		detector.Observe(ctx, "targeted", user, ldvalue.Int(0), 0, detail(0, target), nil)
+		detector.Observe(ctx, "rollout", user, ldvalue.Int(0), 0, detail(0, fallthrough_), nil)
+		detector.Observe(ctx, "rollout", user, ldvalue.Int(0), 0, detail(1, fallthrough_), nil)
+		detector.Observe(ctx, "mixed", user, ldvalue.Int(0), 0, detail(0, fallthrough_), nil)
+		detector.Observe(ctx, "mixed", user, ldvalue.Int(0), 0, detail(1, rule), nil)
+	}
+
+	observe()
+	if report := detector.Report(); len(report) != 0 {
+		t.Errorf("report - expected none before the threshold; got %v\n", report)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	observe()
+	report := detector.Report()
+	if len(report) != 3 {
+		t.Fatalf("report - expected %d; got %v\n", 3, report)
+	}
+
+	expected := []struct {
+		key             string
+		singleVariation bool
+		untargeted      bool
+		variations      string
+		reasons         string
+	}{
+		{"rollout", false, true, "[0 1]", "[FALLTHROUGH]"},
+		{"single", true, true, "[0]", "[FALLTHROUGH]"},
+		{"targeted", true, false, "[0]", "[TARGET_MATCH]"},
+	}
+	for i, exp := range expected {
+		sf := report[i]
+		if sf.Key != exp.key || sf.SingleVariation != exp.singleVariation || sf.Untargeted != exp.untargeted {
+			t.Errorf("%s - unexpected: %+v\n", exp.key, sf)
+		}
+		if fmt.Sprint(sf.Variations) != exp.variations || fmt.Sprint(sf.Reasons) != exp.reasons {
+			t.Errorf("%s - expected %s and %s; got %v and %v\n", exp.key, exp.variations, exp.reasons, sf.Variations, sf.Reasons)
+		}
+		if sf.Since != sf.FirstSeen || !sf.LastSeen.After(sf.FirstSeen) {
+			t.Errorf("%s - unexpected times: %+v\n", exp.key, sf)
+		}
+	}
+}
+
+func TestStaleFlagDetectorWindow(t *testing.T) {
+	detector := goldhook.NewStaleFlagDetector(100*time.Millisecond, 300*time.Millisecond)
+	ctx := context.Background()
+	user := lduser.NewUser("stale")
+
+	on := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthrough())
+	off := ldreason.NewEvaluationDetail(ldvalue.Bool(false), 1, ldreason.NewEvalReasonRuleMatch(0, "rule"))
+
+	detector.Observe(ctx, "flip", user, ldvalue.Bool(false), 0, on, nil)
+	detector.Observe(ctx, "forgotten", user, ldvalue.Bool(false), 0, on, nil)
+	time.Sleep(100 * time.Millisecond)
+	flipped := time.Now()
+	detector.Observe(ctx, "flip", user, ldvalue.Bool(false), 0, off, nil)
+
+	// the other variation (and rule) falls out of the window, but it is too
+	// soon since it was last served
+	time.Sleep(150 * time.Millisecond)
+	detector.Observe(ctx, "flip", user, ldvalue.Bool(false), 0, on, nil)
+	if report := detector.Report(); len(report) != 0 {
+		t.Errorf("report - expected none before the threshold; got %v\n", report)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	detector.Observe(ctx, "flip", user, ldvalue.Bool(false), 0, on, nil)
+	report := detector.Report()
+	if len(report) != 1 {
+		t.Fatalf("report - expected %d; got %v\n", 1, report)
+	}
+	sf := report[0]
+	if sf.Key != "flip" || !sf.SingleVariation || !sf.Untargeted {
+		t.Errorf("flip - unexpected: %+v\n", sf)
+	}
+	if sf.Since.Before(flipped) || sf.Since.After(flipped.Add(50*time.Millisecond)) {
+		t.Errorf("flip - expected since %v; got %v\n", flipped, sf.Since)
+	}
+}
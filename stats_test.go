@@ -0,0 +1,92 @@
+package goldhook_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/nelz9999/goldhook"
+)
+
+func TestStatsObserver(t *testing.T) {
+	stats := goldhook.NewStatsObserver(10*time.Millisecond, time.Millisecond, 10*time.Millisecond)
+	user := lduser.NewUser("stats")
+	ctx := context.Background()
+
+	first := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthrough())
+	second := ldreason.NewEvaluationDetail(ldvalue.Bool(false), 1, ldreason.NewEvalReasonTargetMatch())
+	notReady := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorClientNotReady, ldvalue.Bool(false))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats.Observe(ctx, "flag", user, ldvalue.Bool(false), 500*time.Microsecond, first, nil)
+			stats.Observe(ctx, "flag", user, ldvalue.Bool(false), 5*time.Millisecond, second, nil)
+			stats.Observe(ctx, "flag", user, ldvalue.Bool(false), time.Second, notReady, errors.New("not ready"))
+			stats.Observe(ctx, "other", user, ldvalue.Bool(false), 0, first, nil)
+		}()
+	}
+	wg.Wait()
+
+	snap := stats.Snapshot()
+	if len(snap) != 2 {
+		t.Fatalf("flags - expected %d; got %v\n", 2, snap)
+	}
+	fs := snap["flag"]
+	if fs.Key != "flag" || fs.Evaluations != 30 || fs.Fallbacks != 10 {
+		t.Errorf("counts - expected 30 with 10 fallbacks; got %d with %d\n", fs.Evaluations, fs.Fallbacks)
+	}
+	if fmt.Sprint(fs.Variations) != "map[0:10 1:10]" {
+		t.Errorf("variations - unexpected: %v\n", fs.Variations)
+	}
+	if fmt.Sprint(fs.Reasons) != "map[ERROR:10 FALLTHROUGH:10 TARGET_MATCH:10]" {
+		t.Errorf("reasons - unexpected: %v\n", fs.Reasons)
+	}
+	if fmt.Sprint(fs.Errors) != "map[CLIENT_NOT_READY:10]" {
+		t.Errorf("errors - unexpected: %v\n", fs.Errors)
+	}
+	if fmt.Sprint(fs.Latency.Bounds) != "[1ms 10ms]" {
+		t.Errorf("bounds - expected sorted and unique; got %v\n", fs.Latency.Bounds)
+	}
+	if fmt.Sprint(fs.Latency.Counts) != "[10 10 10]" || fs.Latency.Count() != 30 {
+		t.Errorf("latency - unexpected: %v\n", fs.Latency.Counts)
+	}
+	if expected := 10 * (time.Second + 5*time.Millisecond + 500*time.Microsecond); fs.Latency.Sum != expected {
+		t.Errorf("latency - expected sum %v; got %v\n", expected, fs.Latency.Sum)
+	}
+
+	// the snapshot is not affected by later evaluations
+	stats.Observe(ctx, "flag", user, ldvalue.Bool(false), 0, first, nil)
+	if fs.Evaluations != 30 || fs.Variations[0] != 10 || fs.Latency.Counts[0] != 10 {
+		t.Errorf("snapshot - changed underneath: %+v\n", fs)
+	}
+	if again := stats.Snapshot()["flag"]; again.Evaluations != 31 {
+		t.Errorf("snapshot - expected %d; got %d\n", 31, again.Evaluations)
+	}
+
+	stats.Reset()
+	if snap := stats.Snapshot(); len(snap) != 0 {
+		t.Errorf("reset - expected empty; got %v\n", snap)
+	}
+}
+
+func TestStatsObserverDefaultBounds(t *testing.T) {
+	stats := goldhook.NewStatsObserver()
+	stats.Observe(context.Background(), "flag", lduser.NewUser("stats"), ldvalue.Null(), time.Hour, ldreason.EvaluationDetail{}, nil)
+	fs := stats.Snapshot()["flag"]
+	if len(fs.Latency.Bounds) != len(goldhook.DefaultLatencyBounds) {
+		t.Errorf("bounds - expected %v; got %v\n", goldhook.DefaultLatencyBounds, fs.Latency.Bounds)
+	}
+	if overflow := fs.Latency.Counts[len(fs.Latency.Counts)-1]; overflow != 1 {
+		t.Errorf("overflow - expected %d; got %d\n", 1, overflow)
+	}
+}
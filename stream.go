@@ -0,0 +1,191 @@
+package goldhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// StreamHandler is an Observer which is also an http.Handler, streaming each
+// evaluation to its subscribers as Server-Sent Events, as they happen. The
+// evaluations streamed can be filtered with the query parameters "flag",
+// "user" (a user key), "reason" (a reason kind) and "error" ("true" or
+// "false"), as per DebugHandler.
+//
+// Each subscriber has a bounded buffer; evaluations which don't fit are
+// dropped, rather than holding up the evaluation, and the subscriber is sent
+// a "dropped" event with the number lost, before its next evaluation or
+// heartbeat. Observe takes no lock, so
+// evaluations are not held up by one another, nor by the subscribers coming
+// and going.
+type StreamHandler struct {
+	buffer    int
+	heartbeat time.Duration
+
+	// subscribers holds a []*streamSubscriber, which is replaced (under mu)
+	// rather than changed, so that Observe can range over it without a lock
+	subscribers atomic.Value
+	mu          sync.Mutex
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+type streamSubscriber struct {
+	dropped uint64
+
+	filter debugFilter
+	events chan debugEvaluation
+}
+
+// NewStreamHandler buffers up to the given number of evaluations per
+// subscriber, and writes a comment to each stream at every heartbeat, to keep
+// it from being closed by proxies along the way when it is idle.
+func NewStreamHandler(buffer int, heartbeat time.Duration) (*StreamHandler, error) {
+	if buffer < 1 {
+		return nil, fmt.Errorf("buffer must be positive")
+	}
+	if heartbeat <= 0 {
+		return nil, fmt.Errorf("heartbeat must be positive")
+	}
+	sh := &StreamHandler{
+		buffer:    buffer,
+		heartbeat: heartbeat,
+		done:      make(chan struct{}),
+	}
+	sh.subscribers.Store([]*streamSubscriber{})
+	return sh, nil
+}
+
+// Observe conforms to the Observer interface
+func (sh *StreamHandler) Observe(
+	_ context.Context,
+	key string,
+	user lduser.User,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	subscribers := sh.subscribers.Load().([]*streamSubscriber)
+	if len(subscribers) == 0 {
+		return
+	}
+	de := newDebugEvaluation(key, user, callsiteDefault, elapsed, detail, evalErr)
+	for _, sub := range subscribers {
+		if !sub.filter.matches(de) {
+			continue
+		}
+		select {
+		case sub.events <- de:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
+
+// Subscribers is the number of streams currently open
+func (sh *StreamHandler) Subscribers() int {
+	return len(sh.subscribers.Load().([]*streamSubscriber))
+}
+
+// subscribe adds sub to (or, if not add, removes it from) a fresh copy of the
+// subscribers
+func (sh *StreamHandler) subscribe(sub *streamSubscriber, add bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	old := sh.subscribers.Load().([]*streamSubscriber)
+	subscribers := make([]*streamSubscriber, 0, len(old)+1)
+	for _, s := range old {
+		if s != sub {
+			subscribers = append(subscribers, s)
+		}
+	}
+	if add {
+		subscribers = append(subscribers, sub)
+	}
+	sh.subscribers.Store(subscribers)
+}
+
+// Close ends every stream, and refuses any more
+func (sh *StreamHandler) Close() {
+	sh.closeOnce.Do(func() {
+		close(sh.done)
+	})
+}
+
+// ServeHTTP conforms to the http.Handler interface
+func (sh *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	select {
+	case <-sh.done:
+		http.Error(w, "stream is closed", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	sub := &streamSubscriber{
+		filter: newDebugFilter(r.URL.Query()),
+		events: make(chan debugEvaluation, sh.buffer),
+	}
+	sh.subscribe(sub, true)
+	defer sh.subscribe(sub, false)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sh.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sh.done:
+			return
+		case <-heartbeat.C:
+			// evaluations dropped since the last one sent would otherwise
+			// go unreported until the next
+			if err := sub.writeDropped(w); err != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case de := <-sub.events:
+			if err := sub.writeDropped(w); err != nil {
+				return
+			}
+			data, err := json.Marshal(de)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: evaluation\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeDropped sends a "dropped" event, if any evaluations have been dropped
+// since it was last sent
+func (sub *streamSubscriber) writeDropped(w http.ResponseWriter) error {
+	if dropped := atomic.SwapUint64(&sub.dropped, 0); dropped > 0 {
+		_, err := fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", dropped)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/lduser"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+type ctxKey string
+
+// gatedObserver signals when it starts observing, then waits for the gate
+type gatedObserver struct {
+	started chan string
+	gate    chan struct{}
+
+	mu   sync.Mutex
+	keys []string
+	errs []error
+}
+
+func newGatedObserver() *gatedObserver {
+	return &gatedObserver{
+		started: make(chan string, 10),
+		gate:    make(chan struct{}),
+	}
+}
+
+func (g *gatedObserver) Observe(
+	ctx context.Context,
+	key string,
+	_ ldcontext.Context,
+	_ ldvalue.Value,
+	_ time.Duration,
+	_ ldreason.EvaluationDetail,
+	_ error,
+) {
+	g.started <- key
+	<-g.gate
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.keys = append(g.keys, key)
+	if ctx.Value(ctxKey("key")) != key {
+		g.errs = append(g.errs, fmt.Errorf("lost context value for %q", key))
+	}
+	if err := ctx.Err(); err != nil {
+		g.errs = append(g.errs, err)
+	}
+}
+
+func TestAsyncObserver(t *testing.T) {
+	testCases := []struct {
+		policy   goldhook.DropPolicy
+		queued   uint64
+		expected []string
+	}{
+		// the third is dropped on arrival
+		{goldhook.DropNewest, 2, []string{"first", "second"}},
+		// the second is dropped to make room for the third
+		{goldhook.DropOldest, 3, []string{"first", "third"}},
+	}
+	for _, tc := range testCases {
+		gated := newGatedObserver()
+		ao, err := goldhook.NewAsyncObserver(gated, 1, 1, tc.policy)
+		if err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+
+		user := lduser.NewUser("async")
+		observe := func(key string) {
+			// the request is long gone by the time the worker gets to it
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey("key"), key))
+			cancel()
+			ao.Observe(ctx, key, user, ldvalue.Bool(true), 0, ldreason.EvaluationDetail{}, nil)
+		}
+
+		observe("first")
+		// make sure the worker is holding the first, so the queue is empty
+		<-gated.started
+		observe("second")
+		observe("third")
+
+		if ao.Queued() != tc.queued || ao.Dropped() != 1 || ao.Pending() != 1 {
+			t.Errorf("%d: counts - expected %d/1/1; got %d/%d/%d\n", tc.policy, tc.queued, ao.Queued(), ao.Dropped(), ao.Pending())
+		}
+
+		// a Close which times out leaves the workers draining
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := ao.Close(ctx); err != context.DeadlineExceeded {
+			t.Errorf("%d: close - expected %v; got %v\n", tc.policy, context.DeadlineExceeded, err)
+		}
+		cancel()
+
+		observe("fourth")
+		if ao.Dropped() != 2 {
+			t.Errorf("%d: after close - expected %d dropped; got %d\n", tc.policy, 2, ao.Dropped())
+		}
+
+		close(gated.gate)
+		if err := ao.Close(context.Background()); err != nil {
+			t.Errorf("%d: close - unexpected: %v\n", tc.policy, err)
+		}
+
+		if len(gated.keys) != len(tc.expected) {
+			t.Fatalf("%d: delivered - expected %v; got %v\n", tc.policy, tc.expected, gated.keys)
+		}
+		for i, exp := range tc.expected {
+			if gated.keys[i] != exp {
+				t.Errorf("%d: delivered - expected %v; got %v\n", tc.policy, tc.expected, gated.keys)
+			}
+		}
+		for _, err := range gated.errs {
+			t.Errorf("%d: context - expected detached; got %v\n", tc.policy, err)
+		}
+	}
+}
+
+func TestAsyncObserverBlock(t *testing.T) {
+	gated := newGatedObserver()
+	ao, err := goldhook.NewAsyncObserver(gated, 1, 1, goldhook.Block)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	user := lduser.NewUser("async")
+	ctx := context.Background()
+
+	ao.Observe(ctx, "first", user, ldvalue.Bool(true), 0, ldreason.EvaluationDetail{}, nil)
+	<-gated.started
+	ao.Observe(ctx, "second", user, ldvalue.Bool(true), 0, ldreason.EvaluationDetail{}, nil)
+
+	done := make(chan struct{})
+	go func() {
+		ao.Observe(ctx, "third", user, ldvalue.Bool(true), 0, ldreason.EvaluationDetail{}, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Errorf("expected Observe to block on a full queue\n")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(gated.gate)
+	<-done
+	if err := ao.Close(ctx); err != nil {
+		t.Errorf("close - unexpected: %v\n", err)
+	}
+	if ao.Queued() != 3 || ao.Dropped() != 0 {
+		t.Errorf("counts - expected 3/0; got %d/%d\n", ao.Queued(), ao.Dropped())
+	}
+}
+
+func TestAsyncObserverInvalid(t *testing.T) {
+	if _, err := goldhook.NewAsyncObserver(nil, 1, 1, goldhook.DropNewest); err == nil {
+		t.Errorf("expected error for nil observer\n")
+	}
+	if _, err := goldhook.NewAsyncObserver(newGatedObserver(), 0, 1, goldhook.DropNewest); err == nil {
+		t.Errorf("expected error for empty queue\n")
+	}
+	if _, err := goldhook.NewAsyncObserver(newGatedObserver(), 1, 0, goldhook.DropNewest); err == nil {
+		t.Errorf("expected error for no workers\n")
+	}
+}
+
+func TestAsyncObserverPanics(t *testing.T) {
+	bad := goldhook.ObserverFunc(
+		func(context.Context, string, ldcontext.Context, ldvalue.Value, time.Duration, ldreason.EvaluationDetail, error) {
+			panic("async")
+		},
+	)
+	ao, err := goldhook.NewAsyncObserver(bad, 1, 1, goldhook.Block)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err := goldhook.NewEvaluator(context.Background(), goldhooktest.NewFake(), ao)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var panics []*goldhook.ObserverPanic
+	hooked = hooked.WithPanicHandler(func(p *goldhook.ObserverPanic) {
+		panics = append(panics, p)
+	})
+
+	hooked.BoolVariation("first", ldcontext.New("async"), false)
+	if err := ao.Close(context.Background()); err != nil {
+		t.Errorf("close - unexpected: %v\n", err)
+	}
+
+	// the worker hands the panic to the evaluator's handler
+	if len(panics) != 1 {
+		t.Fatalf("panics - expected %d; got %d\n", 1, len(panics))
+	}
+	if p := panics[0]; p.Key != "first" || p.Index != -1 || p.Value != "async" {
+		t.Errorf("panic - expected %q/%d/%q; got %q/%d/%v\n", "first", -1, "async", p.Key, p.Index, p.Value)
+	}
+}
@@ -0,0 +1,265 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// Client is the interface that describes the methods on an LDClient beyond
+// those of the Evaluator, which an ObservedClient also observes. It leaves out
+// AllFlagsState, whose flagstate package differs between versions of the SDK.
+// The v7 module observes it for the v7 SDK, via ObservedClient.ObserveAllFlags.
+//
+// Nothing observes the AllFlagsState of the v6 SDK for you: an ObservedClient
+// has no AllFlagsState of its own, so the flags a v6 LDClient bootstraps are
+// neither observed nor intercepted unless you call its AllFlagsState, hand
+// the detail of each flag in the state to ObserveAllFlags, and serve the
+// state rebuilt with any details that returns (as the v7 module does).
+type Client interface {
+	Evaluator
+	Identify(ldctx ldcontext.Context) error
+	TrackEvent(eventName string, ldctx ldcontext.Context) error
+	TrackData(eventName string, ldctx ldcontext.Context, data ldvalue.Value) error
+	TrackMetric(eventName string, ldctx ldcontext.Context, metricValue float64, data ldvalue.Value) error
+}
+
+// ClientObserver is interested in the operations of a Client other than the
+// evaluation of individual flags. Any of its callbacks may be nil.
+type ClientObserver struct {
+	// OnAllFlagsState is invoked once per call to ObserveAllFlags (i.e. to
+	// AllFlagsState), with the detail served for each flag, after the
+	// evaluation of each has been observed
+	OnAllFlagsState func(ctx context.Context, ldctx ldcontext.Context, flags map[string]ldreason.EvaluationDetail, elapsed time.Duration)
+
+	// OnIdentify is invoked for every call to Identify
+	OnIdentify func(ctx context.Context, ldctx ldcontext.Context, err error)
+
+	// OnTrackEvent is invoked for every call to TrackEvent
+	OnTrackEvent func(ctx context.Context, eventName string, ldctx ldcontext.Context, err error)
+
+	// OnTrackData is invoked for every call to TrackData
+	OnTrackData func(ctx context.Context, eventName string, ldctx ldcontext.Context, data ldvalue.Value, err error)
+
+	// OnTrackMetric is invoked for every call to TrackMetric
+	OnTrackMetric func(ctx context.Context, eventName string, ldctx ldcontext.Context, metricValue float64, data ldvalue.Value, err error)
+}
+
+// ObservedClient is a drop-in replacement for an LDClient, which observes
+// every method of the Client interface. Evaluations of individual flags are
+// handled by its ObservedEvaluator, as are the flags passed to
+// ObserveAllFlags, so that flags bootstrapped to browsers show up in the same
+// telemetry (and are subject to the same Interceptors and Registry) as the
+// rest. Its ClientObservers share the PanicHandler and quarantine of the
+// ObservedEvaluator.
+type ObservedClient struct {
+	*ObservedEvaluator
+	client            Client
+	observers         []ClientObserver
+	clientQuarantines []*quarantine
+}
+
+// NewObservedClient wraps the ObservedEvaluator (which must itself wrap a
+// Client) with the given observers of the other Client methods.
+func NewObservedClient(oe *ObservedEvaluator, observers ...ClientObserver) (*ObservedClient, error) {
+	if oe == nil {
+		return nil, fmt.Errorf("evaluator must not be nil")
+	}
+	client, ok := oe.client.(Client)
+	if !ok {
+		return nil, fmt.Errorf("evaluator must wrap a Client; got %T", oe.client)
+	}
+	return &ObservedClient{
+		ObservedEvaluator: oe,
+		client:            client,
+		observers:         observers,
+	}, nil
+}
+
+// Client is the Client which the ObservedClient wraps, for wrappers which
+// observe the methods it leaves out, such as AllFlagsState
+func (oc *ObservedClient) Client() Client {
+	return oc.client
+}
+
+func (oc *ObservedClient) WithContext(c context.Context) Evaluator {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithContext(c).(*ObservedEvaluator)
+	return &cp
+}
+
+// WithInterceptors is as per ObservedEvaluator.WithInterceptors, but returns
+// a copy of the ObservedClient
+func (oc *ObservedClient) WithInterceptors(interceptors ...Interceptor) (*ObservedClient, error) {
+	oe, err := oc.ObservedEvaluator.WithInterceptors(interceptors...)
+	if err != nil {
+		return nil, err
+	}
+	cp := *oc
+	cp.ObservedEvaluator = oe
+	return &cp, nil
+}
+
+// WithPanicHandler is as per ObservedEvaluator.WithPanicHandler, but returns
+// a copy of the ObservedClient, whose ClientObservers report to fn too
+func (oc *ObservedClient) WithPanicHandler(fn PanicHandler) *ObservedClient {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithPanicHandler(fn)
+	return &cp
+}
+
+// WithQuarantine is as per ObservedEvaluator.WithQuarantine, but returns a
+// copy of the ObservedClient, whose ClientObservers are quarantined too
+func (oc *ObservedClient) WithQuarantine(limit int, window, cooldown time.Duration) *ObservedClient {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithQuarantine(limit, window, cooldown)
+	cp.clientQuarantines = newQuarantines(len(oc.observers), limit, window, cooldown)
+	return &cp
+}
+
+// WithRegistry is as per ObservedEvaluator.WithRegistry, but returns a copy
+// of the ObservedClient
+func (oc *ObservedClient) WithRegistry(r *Registry) *ObservedClient {
+	cp := *oc
+	cp.ObservedEvaluator = oc.ObservedEvaluator.WithRegistry(r)
+	return &cp
+}
+
+// ObserveAllFlags runs each of the flags served by a single call to
+// AllFlagsState through the same chain as an evaluation of AllFlagsKind, in
+// order of key, with a null callsite default, and the given detail standing
+// in for the client beneath the Interceptors; as the flags are not known
+// beforehand, this all happens after the call. It then notifies the
+// ClientObservers, and returns the details of any flags whose result an
+// Interceptor changed (as Override would), for the caller to serve instead.
+// Flags changed to null (as the callsite default of KillSwitch would be) are
+// left as they were served.
+func (oc *ObservedClient) ObserveAllFlags(ldctx ldcontext.Context, flags map[string]ldreason.EvaluationDetail, elapsed time.Duration) map[string]ldreason.EvaluationDetail {
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var changed map[string]ldreason.EvaluationDetail
+	for _, key := range keys {
+		served := flags[key]
+		req := EvaluationRequest{
+			Key:             key,
+			Context:         ldctx,
+			CallsiteDefault: ldvalue.Null(),
+			Kind:            AllFlagsKind,
+		}
+		detail, _ := oc.evaluateWith(oc.ctx, req, func(context.Context, EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			return served, nil
+		})
+		// an Interceptor serving the callsite default (as KillSwitch does)
+		// can only serve null here, which would turn a flag that is on into
+		// null in the browser; the flag is left as it was served instead
+		if !sameDetail(detail, served) && !detail.Value.IsNull() {
+			if changed == nil {
+				changed = map[string]ldreason.EvaluationDetail{}
+			}
+			changed[key] = detail
+		}
+	}
+
+	if len(changed) > 0 {
+		merged := make(map[string]ldreason.EvaluationDetail, len(flags))
+		for key, detail := range flags {
+			merged[key] = detail
+		}
+		for key, detail := range changed {
+			merged[key] = detail
+		}
+		flags = merged
+	}
+	oc.notify("AllFlagsState", func(co ClientObserver) {
+		if co.OnAllFlagsState != nil {
+			co.OnAllFlagsState(oc.ctx, ldctx, flags, elapsed)
+		}
+	})
+	return changed
+}
+
+// Identify conforms to the Client interface
+func (oc *ObservedClient) Identify(ldctx ldcontext.Context) error {
+	err := oc.client.Identify(ldctx)
+	oc.notify("Identify", func(co ClientObserver) {
+		if co.OnIdentify != nil {
+			co.OnIdentify(oc.ctx, ldctx, err)
+		}
+	})
+	return err
+}
+
+// TrackEvent conforms to the Client interface
+func (oc *ObservedClient) TrackEvent(eventName string, ldctx ldcontext.Context) error {
+	err := oc.client.TrackEvent(eventName, ldctx)
+	oc.notify(eventName, func(co ClientObserver) {
+		if co.OnTrackEvent != nil {
+			co.OnTrackEvent(oc.ctx, eventName, ldctx, err)
+		}
+	})
+	return err
+}
+
+// TrackData conforms to the Client interface
+func (oc *ObservedClient) TrackData(eventName string, ldctx ldcontext.Context, data ldvalue.Value) error {
+	err := oc.client.TrackData(eventName, ldctx, data)
+	oc.notify(eventName, func(co ClientObserver) {
+		if co.OnTrackData != nil {
+			co.OnTrackData(oc.ctx, eventName, ldctx, data, err)
+		}
+	})
+	return err
+}
+
+// TrackMetric conforms to the Client interface
+func (oc *ObservedClient) TrackMetric(eventName string, ldctx ldcontext.Context, metricValue float64, data ldvalue.Value) error {
+	err := oc.client.TrackMetric(eventName, ldctx, metricValue, data)
+	oc.notify(eventName, func(co ClientObserver) {
+		if co.OnTrackMetric != nil {
+			co.OnTrackMetric(oc.ctx, eventName, ldctx, metricValue, data, err)
+		}
+	})
+	return err
+}
+
+// notify hands each observer to fn, recovering any panic so that it doesn't
+// take down the operation (or the observers after it)
+func (oc *ObservedClient) notify(key string, fn func(co ClientObserver)) {
+	for i, co := range oc.observers {
+		if oc.clientQuarantines != nil && oc.clientQuarantines[i].active(time.Now()) {
+			continue
+		}
+		func() {
+			defer oc.recoverClient(i, key)
+			fn(co)
+		}()
+	}
+}
+
+func (oc *ObservedClient) recoverClient(i int, key string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	p := &ObserverPanic{
+		Observer: oc.observers[i],
+		Index:    i,
+		Key:      key,
+		Value:    r,
+		Stack:    debug.Stack(),
+	}
+	if oc.clientQuarantines != nil {
+		p.Quarantined = oc.clientQuarantines[i].record(time.Now())
+	}
+	handlePanic(oc.onPanic, p)
+}
@@ -0,0 +1,215 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ld "github.com/launchdarkly/go-server-sdk/v7"
+
+	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+func TestObservedClient(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer client.Close()
+	flags := map[string]ldreason.EvaluationDetail{
+		"banner-" + now:   ldreason.NewEvaluationDetail(ldvalue.String(now), 1, ldreason.NewEvalReasonFallthrough()),
+		"checkout-" + now: ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonTargetMatch()),
+	}
+
+	if _, err := goldhook.NewObservedClient(nil); err == nil {
+		t.Errorf("expected an error for a nil evaluator\n")
+	}
+	plain, err := goldhook.NewEvaluator(context.Background(), goldhooktest.NewFake())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, err := goldhook.NewObservedClient(plain); err == nil {
+		t.Errorf("expected an error for an evaluator without a Client\n")
+	}
+
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := goldhook.NewEvaluator(context.Background(), client, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	type ctxKey string
+	var states, identities int
+	tracked := []string{}
+	observed, err := goldhook.NewObservedClient(hooked.WithPanicHandler(func(*goldhook.ObserverPanic) {}),
+		goldhook.ClientObserver{
+			OnAllFlagsState: func(_ context.Context, _ ldcontext.Context, flags map[string]ldreason.EvaluationDetail, _ time.Duration) {
+				states += len(flags)
+			},
+			OnIdentify: func(ctx context.Context, ldctx ldcontext.Context, err error) {
+				if ctx.Value(ctxKey("now")) == now && ldctx.Key() == now && err == nil {
+					identities++
+				}
+			},
+			OnTrackEvent: func(_ context.Context, eventName string, _ ldcontext.Context, _ error) {
+				tracked = append(tracked, eventName)
+			},
+			OnTrackData: func(_ context.Context, eventName string, _ ldcontext.Context, data ldvalue.Value, _ error) {
+				tracked = append(tracked, eventName+":"+data.StringValue())
+			},
+			OnTrackMetric: func(_ context.Context, eventName string, _ ldcontext.Context, metricValue float64, _ ldvalue.Value, _ error) {
+				tracked = append(tracked, fmt.Sprintf("%s:%v", eventName, metricValue))
+			},
+		},
+		goldhook.ClientObserver{
+			OnTrackEvent: func(context.Context, string, ldcontext.Context, error) {
+				panic(now)
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var _ goldhook.Client = observed
+
+	ldctx := ldcontext.New(now)
+	if changed := observed.ObserveAllFlags(ldctx, flags, time.Millisecond); len(changed) != 0 {
+		t.Errorf("unexpected: %v\n", changed)
+	}
+	if states != 2 {
+		t.Errorf("expected %d flags; got %d\n", 2, states)
+	}
+
+	// each flag observed shows up as an evaluation
+	recorder.AssertEvaluated(t, "banner-"+now, 1)
+	recorder.AssertEvaluated(t, "checkout-"+now, 1)
+	recorder.AssertReason(t, "checkout-"+now, ldreason.EvalReasonTargetMatch, 1)
+	ev := recorder.Of("banner-" + now)[0]
+	if ev.Detail.Value.StringValue() != now || ev.Detail.VariationIndex.IntValue() != 1 || !ev.CallsiteDefault.IsNull() {
+		t.Errorf("unexpected: %+v\n", ev)
+	}
+
+	// individual evaluations are observed as ever
+	observed.BoolVariation("checkout-"+now, ldctx, false)
+	recorder.AssertEvaluated(t, "checkout-"+now, 2)
+	recorder.AssertReason(t, "checkout-"+now, ldreason.EvalReasonError, 1)
+
+	ctx := context.WithValue(context.Background(), ctxKey("now"), now)
+	contextual := observed.WithContext(ctx).(*goldhook.ObservedClient)
+	if err := contextual.Identify(ldctx); err != nil {
+		t.Errorf("unexpected: %v\n", err)
+	}
+	if identities != 1 {
+		t.Errorf("expected %d; got %d\n", 1, identities)
+	}
+
+	observed.TrackEvent("viewed", ldctx)
+	observed.TrackData("clicked", ldctx, ldvalue.String(now))
+	observed.TrackMetric("spent", ldctx, 9.99, ldvalue.Null())
+	if expected := fmt.Sprintf("[viewed clicked:%s spent:9.99]", now); fmt.Sprint(tracked) != expected {
+		t.Errorf("expected %s; got %v\n", expected, tracked)
+	}
+}
+
+func TestObservedClientChain(t *testing.T) {
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer client.Close()
+	flags := map[string]ldreason.EvaluationDetail{
+		"banner": ldreason.NewEvaluationDetail(ldvalue.String("served"), 0, ldreason.NewEvalReasonFallthrough()),
+		"legacy": ldreason.NewEvaluationDetail(ldvalue.Bool(true), 1, ldreason.NewEvalReasonFallthrough()),
+	}
+
+	violations := []*goldhook.FlagViolation{}
+	registry, err := goldhook.NewRegistry(
+		func(_ context.Context, v *goldhook.FlagViolation) {
+			violations = append(violations, v)
+		},
+		goldhook.FlagDeclaration{Key: "legacy", Kind: goldhook.BoolKind, Default: ldvalue.Bool(false), Deprecated: "gone soon"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := goldhook.NewEvaluator(context.Background(), client, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var seen map[string]ldreason.EvaluationDetail
+	tracks := 0
+	observed, err := goldhook.NewObservedClient(hooked,
+		goldhook.ClientObserver{
+			OnAllFlagsState: func(_ context.Context, _ ldcontext.Context, flags map[string]ldreason.EvaluationDetail, _ time.Duration) {
+				seen = flags
+			},
+		},
+		goldhook.ClientObserver{
+			OnTrackEvent: func(context.Context, string, ldcontext.Context, error) {
+				tracks++
+				panic("track")
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// each of these keeps the Client methods of the ObservedClient
+	observed, err = observed.WithInterceptors(
+		goldhook.Override("banner", ldvalue.String("overridden")),
+		goldhook.KillSwitch("legacy"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	panics := []*goldhook.ObserverPanic{}
+	observed = observed.
+		WithRegistry(registry).
+		WithPanicHandler(func(p *goldhook.ObserverPanic) {
+			panics = append(panics, p)
+		}).
+		WithQuarantine(1, time.Minute, 0)
+
+	// the flags go through the interceptors, and the registry
+	ldctx := ldcontext.New("chain")
+	changed := observed.ObserveAllFlags(ldctx, flags, time.Millisecond)
+	if len(changed) != 1 || changed["banner"].Value.StringValue() != "overridden" {
+		t.Errorf("changed - unexpected: %v\n", changed)
+	}
+	if v := seen["banner"].Value.StringValue(); v != "overridden" {
+		t.Errorf("observed flags - expected %q; got %q\n", "overridden", v)
+	}
+	// a flag killed has only the null default to serve, so is left as it was
+	if !seen["legacy"].Value.BoolValue() {
+		t.Errorf("observed flags - unexpected: %v\n", seen)
+	}
+	if ev := recorder.Of("banner"); len(ev) != 1 || ev[0].InterceptedBy != "override:banner" {
+		t.Errorf("banner - unexpected: %+v\n", ev)
+	}
+	if ev := recorder.Of("legacy"); len(ev) != 1 || ev[0].InterceptedBy != "killswitch" {
+		t.Errorf("legacy - unexpected: %+v\n", ev)
+	}
+	if len(violations) != 1 || violations[0].Kind != goldhook.DeprecatedFlag {
+		t.Errorf("violations - unexpected: %v\n", violations)
+	}
+
+	// a panicking ClientObserver is quarantined like any other
+	observed.TrackEvent("first", ldctx)
+	observed.TrackEvent("second", ldctx)
+	if tracks != 1 {
+		t.Errorf("tracks - expected %d; got %d\n", 1, tracks)
+	}
+	if len(panics) != 1 || panics[0].Index != 1 || !panics[0].Quarantined {
+		t.Errorf("panics - unexpected: %+v\n", panics)
+	}
+}
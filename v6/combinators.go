@@ -0,0 +1,167 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// KeyMatcher decides whether a flag key is of interest
+type KeyMatcher func(key string) bool
+
+// ExactKeys matches any of the given flag keys
+func ExactKeys(keys ...string) KeyMatcher {
+	set := map[string]bool{}
+	for _, k := range keys {
+		set[k] = true
+	}
+	return func(key string) bool {
+		return set[key]
+	}
+}
+
+// KeyPrefix matches flag keys which start with the given prefix
+func KeyPrefix(prefix string) KeyMatcher {
+	return func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}
+}
+
+// KeyGlob matches flag keys against a shell pattern, as per path.Match
+func KeyGlob(pattern string) (KeyMatcher, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return func(key string) bool {
+		ok, _ := path.Match(pattern, key)
+		return ok
+	}, nil
+}
+
+// KeyRegexp matches flag keys against a regular expression
+func KeyRegexp(re *regexp.Regexp) (KeyMatcher, error) {
+	if re == nil {
+		return nil, fmt.Errorf("regexp must not be nil")
+	}
+	return re.MatchString, nil
+}
+
+// FilterKeys passes on to the Observer only those evaluations whose flag key matches
+func FilterKeys(o Observer, match KeyMatcher) (Observer, error) {
+	if match == nil {
+		return nil, fmt.Errorf("key matcher must not be nil")
+	}
+	return filter(o, func(key string, _ ldcontext.Context, _ ldreason.EvaluationDetail, _ error) bool {
+		return match(key)
+	})
+}
+
+// FilterReasons passes on to the Observer only those evaluations whose reason
+// is of one of the given kinds
+func FilterReasons(o Observer, kinds ...ldreason.EvalReasonKind) (Observer, error) {
+	set := map[ldreason.EvalReasonKind]bool{}
+	for _, k := range kinds {
+		set[k] = true
+	}
+	return filter(o, func(_ string, _ ldcontext.Context, detail ldreason.EvaluationDetail, _ error) bool {
+		return set[detail.Reason.GetKind()]
+	})
+}
+
+// FilterErrorKinds passes on to the Observer only those evaluations whose reason
+// is an ERROR of one of the given kinds
+func FilterErrorKinds(o Observer, kinds ...ldreason.EvalErrorKind) (Observer, error) {
+	set := map[ldreason.EvalErrorKind]bool{}
+	for _, k := range kinds {
+		set[k] = true
+	}
+	return filter(o, func(_ string, _ ldcontext.Context, detail ldreason.EvaluationDetail, _ error) bool {
+		return detail.Reason.GetKind() == ldreason.EvalReasonError && set[detail.Reason.GetErrorKind()]
+	})
+}
+
+// OnlyErrors passes on to the Observer only those evaluations which returned an error
+func OnlyErrors(o Observer) (Observer, error) {
+	return filter(o, func(_ string, _ ldcontext.Context, _ ldreason.EvaluationDetail, evalErr error) bool {
+		return evalErr != nil
+	})
+}
+
+// Sample passes on to the Observer the given fraction of evaluations. The
+// choice is made by hashing the context key, so a given context is either
+// always or never passed on.
+func Sample(o Observer, rate float64) (Observer, error) {
+	return filter(o, func(_ string, ldctx ldcontext.Context, _ ldreason.EvaluationDetail, _ error) bool {
+		return sampled(ldctx.FullyQualifiedKey(), rate)
+	})
+}
+
+func sampled(key string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	// FNV alone leaves the high bits poorly mixed for similar keys, so
+	// finish it off as MurmurHash3 does
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return float64(x) < rate*math.MaxUint64
+}
+
+// Multi fans each evaluation out to all the given observers, in order
+func Multi(observers ...Observer) (Observer, error) {
+	for _, o := range observers {
+		if o == nil {
+			return nil, fmt.Errorf("observers must not be nil")
+		}
+	}
+	return ObserverFunc(func(
+		ctx context.Context,
+		key string,
+		ldctx ldcontext.Context,
+		callsiteDefault ldvalue.Value,
+		elapsed time.Duration,
+		detail ldreason.EvaluationDetail,
+		evalErr error,
+	) {
+		for _, o := range observers {
+			o.Observe(ctx, key, ldctx, callsiteDefault, elapsed, detail, evalErr)
+		}
+	}), nil
+}
+
+func filter(o Observer, keep func(key string, ldctx ldcontext.Context, detail ldreason.EvaluationDetail, evalErr error) bool) (Observer, error) {
+	if o == nil {
+		return nil, fmt.Errorf("observer must not be nil")
+	}
+	return ObserverFunc(func(
+		ctx context.Context,
+		key string,
+		ldctx ldcontext.Context,
+		callsiteDefault ldvalue.Value,
+		elapsed time.Duration,
+		detail ldreason.EvaluationDetail,
+		evalErr error,
+	) {
+		if keep(key, ldctx, detail, evalErr) {
+			o.Observe(ctx, key, ldctx, callsiteDefault, elapsed, detail, evalErr)
+		}
+	}), nil
+}
@@ -0,0 +1,77 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+func TestFromContext(t *testing.T) {
+	if _, ok := goldhook.FromContext(context.Background()); ok {
+		t.Errorf("expected no evaluator\n")
+	}
+	fake := goldhooktest.NewFake()
+	e, ok := goldhook.FromContext(goldhook.NewContext(context.Background(), fake))
+	if !ok || e != goldhook.Evaluator(fake) {
+		t.Errorf("expected %v; got %v\n", fake, e)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	seen := []interface{}{}
+	observer := goldhook.ObserverFunc(
+		func(ctx context.Context, _ string, _ ldcontext.Context, _ ldvalue.Value, _ time.Duration, _ ldreason.EvaluationDetail, _ error) {
+			seen = append(seen, ctx.Value(ctxKey("request")), ctx.Value(ctxKey("handler")))
+		},
+	)
+	fake := goldhooktest.NewFake().Set("enabled", goldhooktest.Serve(ldvalue.Bool(true), 1))
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake, observer)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// deep code only has the request's context to go on
+	deep := func(ctx context.Context) bool {
+		e, ok := goldhook.FromContext(ctx)
+		if !ok {
+			t.Errorf("expected an evaluator\n")
+			return false
+		}
+		enabled, _ := e.BoolVariation("enabled", ldcontext.New(now), false)
+		return enabled
+	}
+	handler := goldhook.Middleware(hooked)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a value added after the middleware is seen by the observer, too
+		ctx := context.WithValue(r.Context(), ctxKey("handler"), "handled")
+		fmt.Fprint(w, deep(ctx))
+	}))
+	// some earlier middleware, e.g. tracing
+	handler = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey("request"), r.URL.Path)))
+		})
+	}(handler)
+
+	for _, path := range []string{"/first", "/second"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if body := rec.Body.String(); body != "true" {
+			t.Errorf("%s - expected %q; got %q\n", path, "true", body)
+		}
+	}
+	if fmt.Sprint(seen) != "[/first handled /second handled]" {
+		t.Errorf("expected request contexts; got %v\n", seen)
+	}
+}
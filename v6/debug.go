@@ -0,0 +1,290 @@
+package goldhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// DebugHandler is an Observer which keeps the most recent evaluations in a
+// ring buffer, and is also an http.Handler (in the style of /debug/vars)
+// which shows them, along with summaries per flag. Pages are HTML, unless
+// JSON is asked for, via the Accept header or "format=json".
+//
+// The evaluations shown can be filtered with the query parameters "flag",
+// "context" (a context key), "reason" (a reason kind), "error" ("true" or
+// "false") and "limit".
+type DebugHandler struct {
+	stats *StatsObserver
+
+	mu   sync.Mutex
+	ring []debugEvaluation
+	next int
+	full bool
+}
+
+type debugEvaluation struct {
+	Time        time.Time                 `json:"time"`
+	Key         string                    `json:"key"`
+	ContextKind string                    `json:"contextKind"`
+	ContextKey  string                    `json:"contextKey"`
+	Value       ldvalue.Value             `json:"value"`
+	Default     ldvalue.Value             `json:"default"`
+	Variation   ldvalue.OptionalInt       `json:"variation"`
+	Reason      ldreason.EvaluationReason `json:"reason"`
+	Error       string                    `json:"error,omitempty"`
+	Elapsed     time.Duration             `json:"elapsed"`
+}
+
+func newDebugEvaluation(
+	key string,
+	ldctx ldcontext.Context,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) debugEvaluation {
+	de := debugEvaluation{
+		Time:        time.Now(),
+		Key:         key,
+		ContextKind: string(ldctx.Kind()),
+		ContextKey:  ldctx.Key(),
+		Value:       detail.Value,
+		Default:     callsiteDefault,
+		Variation:   detail.VariationIndex,
+		Reason:      detail.Reason,
+		Elapsed:     elapsed,
+	}
+	if evalErr != nil {
+		de.Error = evalErr.Error()
+	}
+	return de
+}
+
+func (de debugEvaluation) errored() bool {
+	return de.Error != "" || de.Reason.GetKind() == ldreason.EvalReasonError
+}
+
+type debugFlag struct {
+	Key         string                             `json:"key"`
+	Evaluations uint64                             `json:"evaluations"`
+	Variations  map[int]uint64                     `json:"variations"`
+	Fallbacks   uint64                             `json:"fallbacks"`
+	Reasons     map[ldreason.EvalReasonKind]uint64 `json:"reasons"`
+	Errors      uint64                             `json:"errors"`
+	MeanElapsed time.Duration                      `json:"meanElapsed"`
+}
+
+// NewDebugHandler keeps the given number of the most recent evaluations
+func NewDebugHandler(capacity int) (*DebugHandler, error) {
+	if capacity < 1 {
+		return nil, fmt.Errorf("capacity must be positive")
+	}
+	return &DebugHandler{
+		stats: NewStatsObserver(),
+		ring:  make([]debugEvaluation, capacity),
+	}, nil
+}
+
+// Observe conforms to the Observer interface
+func (dh *DebugHandler) Observe(
+	ctx context.Context,
+	key string,
+	ldctx ldcontext.Context,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	dh.stats.Observe(ctx, key, ldctx, callsiteDefault, elapsed, detail, evalErr)
+	de := newDebugEvaluation(key, ldctx, callsiteDefault, elapsed, detail, evalErr)
+
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	dh.ring[dh.next] = de
+	dh.next = (dh.next + 1) % len(dh.ring)
+	dh.full = dh.full || dh.next == 0
+}
+
+// debugFilter picks out evaluations by the query parameters "flag",
+// "context", "reason" and "error"
+type debugFilter struct {
+	flag    string
+	ctxKey  string
+	reason  string
+	errored string
+}
+
+func newDebugFilter(q url.Values) debugFilter {
+	return debugFilter{
+		flag:    q.Get("flag"),
+		ctxKey:  q.Get("context"),
+		reason:  q.Get("reason"),
+		errored: q.Get("error"),
+	}
+}
+
+func (df debugFilter) matches(de debugEvaluation) bool {
+	switch {
+	case df.flag != "" && de.Key != df.flag:
+	case df.ctxKey != "" && de.ContextKey != df.ctxKey:
+	case df.reason != "" && !strings.EqualFold(string(de.Reason.GetKind()), df.reason):
+	case df.errored != "" && strconv.FormatBool(de.errored()) != df.errored:
+	default:
+		return true
+	}
+	return false
+}
+
+// recent returns the buffered evaluations which pass the filters in the
+// query, most recent first
+func (dh *DebugHandler) recent(q url.Values) []debugEvaluation {
+	filter := newDebugFilter(q)
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = len(dh.ring)
+	}
+
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	n := dh.next
+	if dh.full {
+		n = len(dh.ring)
+	}
+	evs := []debugEvaluation{}
+	for i := 0; i < n && len(evs) < limit; i++ {
+		de := dh.ring[(dh.next-1-i+len(dh.ring))%len(dh.ring)]
+		if filter.matches(de) {
+			evs = append(evs, de)
+		}
+	}
+	return evs
+}
+
+// summary returns the statistics for every flag seen, ordered by key
+func (dh *DebugHandler) summary() []debugFlag {
+	flags := []debugFlag{}
+	for _, fs := range dh.stats.Snapshot() {
+		df := debugFlag{
+			Key:         fs.Key,
+			Evaluations: fs.Evaluations,
+			Variations:  fs.Variations,
+			Fallbacks:   fs.Fallbacks,
+			Reasons:     fs.Reasons,
+		}
+		for _, n := range fs.Errors {
+			df.Errors += n
+		}
+		if fs.Evaluations > 0 {
+			df.MeanElapsed = fs.Latency.Sum / time.Duration(fs.Evaluations)
+		}
+		flags = append(flags, df)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Key < flags[j].Key
+	})
+	return flags
+}
+
+// ServeHTTP conforms to the http.Handler interface
+func (dh *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page := struct {
+		Query       map[string]string `json:"-"`
+		Flags       []debugFlag       `json:"flags"`
+		Evaluations []debugEvaluation `json:"evaluations"`
+	}{
+		Query:       map[string]string{},
+		Flags:       dh.summary(),
+		Evaluations: dh.recent(r.URL.Query()),
+	}
+	for k := range r.URL.Query() {
+		page.Query[k] = r.URL.Query().Get(k)
+	}
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	debugTemplate.Execute(w, page)
+}
+
+var debugTemplate = template.Must(template.New("debug").Funcs(template.FuncMap{
+	"variation": func(v ldvalue.OptionalInt) string {
+		if idx, ok := v.Get(); ok {
+			return strconv.Itoa(idx)
+		}
+		return "default"
+	},
+	"counts": func(m interface{}) string {
+		parts := []string{}
+		switch m := m.(type) {
+		case map[int]uint64:
+			for k, n := range m {
+				parts = append(parts, fmt.Sprintf("%d: %d", k, n))
+			}
+		case map[ldreason.EvalReasonKind]uint64:
+			for k, n := range m {
+				parts = append(parts, fmt.Sprintf("%s: %d", k, n))
+			}
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ", ")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>flags</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; }
+td.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Flags</h1>
+<table>
+<tr><th>flag</th><th>evaluations</th><th>variations</th><th>fallbacks</th><th>reasons</th><th>errors</th><th>mean elapsed</th></tr>
+{{range .Flags}}<tr>
+<td><a href="?flag={{.Key}}">{{.Key}}</a></td><td>{{.Evaluations}}</td><td>{{counts .Variations}}</td><td>{{.Fallbacks}}</td>
+<td>{{counts .Reasons}}</td><td>{{.Errors}}</td><td>{{.MeanElapsed}}</td>
+</tr>
+{{end}}</table>
+<h1>Recent evaluations</h1>
+<form>
+flag <input name="flag" value="{{.Query.flag}}">
+context <input name="context" value="{{.Query.context}}">
+reason <input name="reason" value="{{.Query.reason}}">
+error <select name="error">
+<option value=""></option>
+<option value="true"{{if eq .Query.error "true"}} selected{{end}}>true</option>
+<option value="false"{{if eq .Query.error "false"}} selected{{end}}>false</option>
+</select>
+limit <input name="limit" value="{{.Query.limit}}">
+<input type="submit" value="filter">
+</form>
+<table>
+<tr><th>time</th><th>flag</th><th>context</th><th>value</th><th>default</th><th>variation</th><th>reason</th><th>error</th><th>elapsed</th></tr>
+{{range .Evaluations}}<tr>
+<td>{{.Time.Format "2006-01-02T15:04:05.000Z07:00"}}</td><td>{{.Key}}</td><td>{{.ContextKind}}:{{.ContextKey}}</td>
+<td>{{.Value.JSONString}}</td><td>{{.Default.JSONString}}</td><td>{{variation .Variation}}</td>
+<td>{{.Reason}}</td><td class="error">{{.Error}}</td><td>{{.Elapsed}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
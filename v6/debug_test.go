@@ -0,0 +1,126 @@
+package goldhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+func TestDebugHandler(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	if _, err := goldhook.NewDebugHandler(0); err == nil {
+		t.Errorf("expected an error\n")
+	}
+	debug, err := goldhook.NewDebugHandler(3)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	fake := goldhooktest.NewFake().
+		Set("enabled", goldhooktest.Serve(ldvalue.Bool(true), 1)).
+		SetFor("enabled", now, goldhooktest.Serve(ldvalue.Bool(false), 0).WithReason(ldreason.NewEvalReasonTargetMatch()))
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake, debug)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	other := ldcontext.New("other")
+	hooked.BoolVariation("enabled", other, false)
+	hooked.BoolVariation("enabled", other, false)
+	hooked.BoolVariation("enabled", ldcontext.New(now), true)
+	hooked.StringVariation("missing", other, "<b>"+now+"</b>")
+
+	get := func(query string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/debug/flags"+query, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		debug.ServeHTTP(rec, req)
+		return rec
+	}
+	type page struct {
+		Flags []struct {
+			Key         string
+			Evaluations uint64
+			Errors      uint64
+		}
+		Evaluations []struct {
+			Key        string
+			ContextKey string
+			Reason     ldreason.EvaluationReason
+			Error      string
+		}
+	}
+	getJSON := func(query string) page {
+		rec := get(query, "application/json")
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s - expected JSON; got %q\n", query, ct)
+		}
+		var p page
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s - unexpected: %v\n", query, err)
+		}
+		return p
+	}
+
+	// the ring only holds the last 3, most recent first, but the summaries are for all
+	p := getJSON("")
+	if len(p.Evaluations) != 3 || p.Evaluations[0].Key != "missing" || p.Evaluations[2].ContextKey != "other" {
+		t.Errorf("unexpected: %+v\n", p.Evaluations)
+	}
+	if len(p.Flags) != 2 || p.Flags[0].Key != "enabled" || p.Flags[0].Evaluations != 3 || p.Flags[1].Errors != 1 {
+		t.Errorf("unexpected: %+v\n", p.Flags)
+	}
+
+	filters := map[string][]string{
+		"?context=" + now:            {"enabled"},
+		"?flag=enabled":              {"enabled", "enabled"},
+		"?reason=target_match":       {"enabled"},
+		"?error=true":                {"missing"},
+		"?error=false&limit=1":       {"enabled"},
+		"?flag=enabled&reason=error": {},
+	}
+	for query, expected := range filters {
+		p := getJSON(query)
+		keys := []string{}
+		for _, ev := range p.Evaluations {
+			keys = append(keys, ev.Key)
+		}
+		if fmt.Sprint(keys) != fmt.Sprint(expected) {
+			t.Errorf("%s - expected %v; got %v\n", query, expected, keys)
+		}
+	}
+
+	rec := get("?format=json", "")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON; got %q\n", ct)
+	}
+
+	rec = get("", "text/html")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected HTML; got %q\n", ct)
+	}
+	body := rec.Body.String()
+	for _, expected := range []string{"enabled", "TARGET_MATCH", "FLAG_NOT_FOUND", "user:" + now} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in:\n%s\n", expected, body)
+		}
+	}
+	if strings.Contains(body, "<b>"+now) {
+		t.Errorf("expected values to be escaped:\n%s\n", body)
+	}
+}
@@ -0,0 +1,122 @@
+package goldhook
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// DedupObserver passes exposures on to another Observer at most once per
+// window, for each combination of flag key, context and variation. Exposures
+// are tracked in a bounded LRU, so that an exposure which has been evicted
+// is passed on again even if its window has not yet elapsed.
+type DedupObserver struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	next            Observer
+	window          time.Duration
+	capacity        int
+	experimentsOnly bool
+
+	mu    sync.Mutex
+	lru   *list.List
+	index map[exposure]*list.Element
+}
+
+type exposure struct {
+	key       string
+	context   string
+	variation int
+}
+
+type exposureEntry struct {
+	exposure
+	seen time.Time
+}
+
+// NewDedupObserver remembers up to capacity distinct exposures. If
+// experimentsOnly is true, only evaluations whose reason is in an experiment
+// are passed on at all.
+func NewDedupObserver(next Observer, window time.Duration, capacity int, experimentsOnly bool) (*DedupObserver, error) {
+	if next == nil {
+		return nil, fmt.Errorf("observer must not be nil")
+	}
+	if capacity < 1 {
+		return nil, fmt.Errorf("capacity must be positive")
+	}
+	return &DedupObserver{
+		next:            next,
+		window:          window,
+		capacity:        capacity,
+		experimentsOnly: experimentsOnly,
+		lru:             list.New(),
+		index:           map[exposure]*list.Element{},
+	}, nil
+}
+
+// Observe conforms to the Observer interface
+func (d *DedupObserver) Observe(
+	ctx context.Context,
+	key string,
+	ldctx ldcontext.Context,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	if d.experimentsOnly && !detail.Reason.IsInExperiment() {
+		return
+	}
+	exp := exposure{
+		key:       key,
+		context:   ldctx.FullyQualifiedKey(),
+		variation: detail.VariationIndex.OrElse(-1),
+	}
+	if d.seen(exp, time.Now()) {
+		d.hits.Add(1)
+		return
+	}
+	d.misses.Add(1)
+	d.next.Observe(ctx, key, ldctx, callsiteDefault, elapsed, detail, evalErr)
+}
+
+// seen reports whether the exposure was already passed on within the window,
+// and if not, records that it is about to be
+func (d *DedupObserver) seen(exp exposure, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.index[exp]; ok {
+		entry := elem.Value.(*exposureEntry)
+		d.lru.MoveToFront(elem)
+		if now.Sub(entry.seen) < d.window {
+			return true
+		}
+		entry.seen = now
+		return false
+	}
+	d.index[exp] = d.lru.PushFront(&exposureEntry{exposure: exp, seen: now})
+	for d.lru.Len() > d.capacity {
+		oldest := d.lru.Back()
+		d.lru.Remove(oldest)
+		delete(d.index, oldest.Value.(*exposureEntry).exposure)
+	}
+	return false
+}
+
+// Hits is the number of exposures which were not passed on, as duplicates
+func (d *DedupObserver) Hits() uint64 {
+	return d.hits.Load()
+}
+
+// Misses is the number of exposures which were passed on
+func (d *DedupObserver) Misses() uint64 {
+	return d.misses.Load()
+}
@@ -0,0 +1,79 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/lduser"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+)
+
+func TestDedupObserver(t *testing.T) {
+	keys := []string{}
+	dedup, err := goldhook.NewDedupObserver(keyRecorder(&keys), 50*time.Millisecond, 2, false)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	ctx := context.Background()
+	alice, bob := lduser.NewUser("alice"), lduser.NewUser("bob")
+	on := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthrough())
+	off := ldreason.NewEvaluationDetail(ldvalue.Bool(false), 1, ldreason.NewEvalReasonFallthrough())
+
+	dedup.Observe(ctx, "a", alice, ldvalue.Bool(false), 0, on, nil)
+	dedup.Observe(ctx, "a", alice, ldvalue.Bool(false), 0, on, nil)
+	// a different variation is a different exposure
+	dedup.Observe(ctx, "b", alice, ldvalue.Bool(false), 0, off, nil)
+	dedup.Observe(ctx, "b", alice, ldvalue.Bool(false), 0, on, nil)
+	if fmt.Sprint(keys) != "[a b b]" || dedup.Hits() != 1 || dedup.Misses() != 3 {
+		t.Errorf("dedup - expected [a b b] with 1/3; got %v with %d/%d\n", keys, dedup.Hits(), dedup.Misses())
+	}
+
+	// only two exposures are remembered, so the first has been evicted
+	dedup.Observe(ctx, "a", alice, ldvalue.Bool(false), 0, on, nil)
+	// ... and a different context is a different exposure
+	dedup.Observe(ctx, "a", bob, ldvalue.Bool(false), 0, on, nil)
+	if fmt.Sprint(keys) != "[a b b a a]" {
+		t.Errorf("evicted - expected [a b b a a]; got %v\n", keys)
+	}
+
+	// once the window has passed, the exposure is passed on again
+	time.Sleep(60 * time.Millisecond)
+	dedup.Observe(ctx, "a", bob, ldvalue.Bool(false), 0, on, nil)
+	dedup.Observe(ctx, "a", bob, ldvalue.Bool(false), 0, on, nil)
+	if fmt.Sprint(keys) != "[a b b a a a]" || dedup.Hits() != 2 || dedup.Misses() != 6 {
+		t.Errorf("window - expected [a b b a a a] with 2/6; got %v with %d/%d\n", keys, dedup.Hits(), dedup.Misses())
+	}
+}
+
+func TestDedupObserverExperimentsOnly(t *testing.T) {
+	keys := []string{}
+	dedup, err := goldhook.NewDedupObserver(keyRecorder(&keys), time.Minute, 10, true)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	ctx := context.Background()
+	user := lduser.NewUser("experiment")
+	plain := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthrough())
+	experiment := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthroughExperiment(true))
+
+	dedup.Observe(ctx, "plain", user, ldvalue.Bool(false), 0, plain, nil)
+	dedup.Observe(ctx, "experiment", user, ldvalue.Bool(false), 0, experiment, nil)
+	dedup.Observe(ctx, "experiment", user, ldvalue.Bool(false), 0, experiment, nil)
+	if fmt.Sprint(keys) != "[experiment]" || dedup.Hits() != 1 || dedup.Misses() != 1 {
+		t.Errorf("experiments - expected [experiment] with 1/1; got %v with %d/%d\n", keys, dedup.Hits(), dedup.Misses())
+	}
+
+	if _, err := goldhook.NewDedupObserver(nil, time.Minute, 10, true); err == nil {
+		t.Errorf("expected error for nil observer\n")
+	}
+	if _, err := goldhook.NewDedupObserver(keyRecorder(&keys), time.Minute, 0, true); err == nil {
+		t.Errorf("expected error for no capacity\n")
+	}
+}
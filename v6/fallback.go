@@ -0,0 +1,281 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// ServedByExtension is the extension which a FallbackEvaluator sets on each
+// evaluation, to the name of the FlagSource which served it.
+const ServedByExtension = "served_by"
+
+// FlagSource is one of the sources of flag values tried in turn by a
+// FallbackEvaluator.
+type FlagSource struct {
+	Name     string
+	Evaluate EvaluateFunc
+}
+
+// ClientSource makes a FlagSource of an EvaluatorCtx, such as the LDClient
+func ClientSource(name string, e EvaluatorCtx) FlagSource {
+	return FlagSource{
+		Name: name,
+		Evaluate: func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			return variation(ctx, e, req)
+		},
+	}
+}
+
+// StaticSource makes a FlagSource of a fixed set of values, keyed by flag.
+// It serves them as Override does, for every context alike.
+func StaticSource(name string, values map[string]ldvalue.Value) FlagSource {
+	cp := make(map[string]ldvalue.Value, len(values))
+	for k, v := range values {
+		cp[k] = v
+	}
+	return FlagSource{
+		Name: name,
+		Evaluate: func(_ context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			value, ok := cp[req.Key]
+			return serveValue(req, value, ok), nil
+		},
+	}
+}
+
+// serveValue serves a value which was found (or not) for the request
+func serveValue(req EvaluationRequest, value ldvalue.Value, ok bool) ldreason.EvaluationDetail {
+	if !ok {
+		return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorFlagNotFound, req.CallsiteDefault)
+	}
+	if !kindMatches(req.Kind, value) {
+		return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorWrongType, req.CallsiteDefault)
+	}
+	return ldreason.EvaluationDetail{Value: value, Reason: ldreason.NewEvalReasonOff()}
+}
+
+// FallbackEvaluator tries each of its FlagSources in turn, e.g. the LDClient,
+// then a Snapshot of last-known-good values, then a StaticSource of defaults,
+// until one serves the evaluation. A source falls through to the next if it
+// returns an error or an ERROR reason (e.g. CLIENT_NOT_READY), or a reason of
+// any kind given to WithFallThrough. If every source falls through, the last
+// one's result is served.
+type FallbackEvaluator struct {
+	sources     []FlagSource
+	fallThrough map[ldreason.EvalReasonKind]bool
+	snapshot    *Snapshot
+	ctx         context.Context
+}
+
+// NewFallbackEvaluator tries the given sources, in order
+func NewFallbackEvaluator(ctx context.Context, sources ...FlagSource) (*FallbackEvaluator, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("sources must not be empty")
+	}
+	for i, src := range sources {
+		if src.Evaluate == nil {
+			return nil, fmt.Errorf("source %d (%q) has no Evaluate", i, src.Name)
+		}
+	}
+	return &FallbackEvaluator{
+		sources: sources,
+		ctx:     orBackground(ctx),
+	}, nil
+}
+
+func (fe *FallbackEvaluator) WithContext(c context.Context) Evaluator {
+	cp := *fe
+	cp.ctx = orBackground(c)
+	return &cp
+}
+
+// WithFallThrough returns a copy of the FallbackEvaluator which also falls
+// through on reasons of the given kinds (in addition to any it already had).
+func (fe *FallbackEvaluator) WithFallThrough(kinds ...ldreason.EvalReasonKind) *FallbackEvaluator {
+	cp := *fe
+	cp.fallThrough = make(map[ldreason.EvalReasonKind]bool, len(fe.fallThrough)+len(kinds))
+	for k := range fe.fallThrough {
+		cp.fallThrough[k] = true
+	}
+	for _, k := range kinds {
+		cp.fallThrough[k] = true
+	}
+	return &cp
+}
+
+// WithSnapshot returns a copy of the FallbackEvaluator which records in the
+// Snapshot every value served by its first source, so as to keep it up to date
+// with the last known good values. Only the variations of flags which are on
+// are recorded; a value served for a flag which is off, or without a
+// variation index (e.g. by a StaticSource), says nothing of what the flag
+// would serve once it is on.
+func (fe *FallbackEvaluator) WithSnapshot(s *Snapshot) *FallbackEvaluator {
+	cp := *fe
+	cp.snapshot = s
+	return &cp
+}
+
+func (fe *FallbackEvaluator) fallsThrough(detail ldreason.EvaluationDetail, err error) bool {
+	kind := detail.Reason.GetKind()
+	return err != nil || kind == ldreason.EvalReasonError || fe.fallThrough[kind]
+}
+
+// recordable is true of a detail served by a flag which is on
+func recordable(detail ldreason.EvaluationDetail) bool {
+	return detail.VariationIndex.IsDefined() && detail.Reason.GetKind() != ldreason.EvalReasonOff
+}
+
+func (fe *FallbackEvaluator) evaluate(ctx context.Context, req EvaluationRequest) (detail ldreason.EvaluationDetail, err error) {
+	var served string
+	for i, src := range fe.sources {
+		served = src.Name
+		detail, err = src.Evaluate(ctx, req)
+		if fe.fallsThrough(detail, err) {
+			continue
+		}
+		if i == 0 && fe.snapshot != nil && recordable(detail) {
+			fe.snapshot.Record(req.Key, detail.Value)
+		}
+		break
+	}
+	SetExtension(ctx, ServedByExtension, served)
+	return detail, err
+}
+
+/* * * BOOL * * */
+
+func (fe *FallbackEvaluator) BoolVariation(key string, ldctx ldcontext.Context, defaultVal bool) (bool, error) {
+	_, detail, err := fe.BoolVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.BoolValue(), err
+}
+
+func (fe *FallbackEvaluator) BoolVariationDetail(key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	_, detail, err := fe.BoolVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.BoolValue(), detail, err
+}
+
+func (fe *FallbackEvaluator) BoolVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal bool) (bool, error) {
+	_, detail, err := fe.BoolVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.BoolValue(), err
+}
+
+func (fe *FallbackEvaluator) BoolVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	detail, err := fe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Bool(defaultVal),
+		Kind:            BoolKind,
+	})
+	return detail.Value.BoolValue(), detail, err
+}
+
+/* * * FLOAT * * */
+
+func (fe *FallbackEvaluator) Float64Variation(key string, ldctx ldcontext.Context, defaultVal float64) (float64, error) {
+	_, detail, err := fe.Float64VariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.Float64Value(), err
+}
+
+func (fe *FallbackEvaluator) Float64VariationDetail(key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	_, detail, err := fe.Float64VariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.Float64Value(), detail, err
+}
+
+func (fe *FallbackEvaluator) Float64VariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal float64) (float64, error) {
+	_, detail, err := fe.Float64VariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.Float64Value(), err
+}
+
+func (fe *FallbackEvaluator) Float64VariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	detail, err := fe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Float64(defaultVal),
+		Kind:            Float64Kind,
+	})
+	return detail.Value.Float64Value(), detail, err
+}
+
+/* * * INT * * */
+
+func (fe *FallbackEvaluator) IntVariation(key string, ldctx ldcontext.Context, defaultVal int) (int, error) {
+	_, detail, err := fe.IntVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.IntValue(), err
+}
+
+func (fe *FallbackEvaluator) IntVariationDetail(key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	_, detail, err := fe.IntVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.IntValue(), detail, err
+}
+
+func (fe *FallbackEvaluator) IntVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal int) (int, error) {
+	_, detail, err := fe.IntVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.IntValue(), err
+}
+
+func (fe *FallbackEvaluator) IntVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	detail, err := fe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Int(defaultVal),
+		Kind:            IntKind,
+	})
+	return detail.Value.IntValue(), detail, err
+}
+
+/* * * JSON * * */
+
+func (fe *FallbackEvaluator) JSONVariation(key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	_, detail, err := fe.JSONVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value, err
+}
+
+func (fe *FallbackEvaluator) JSONVariationDetail(key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	_, detail, err := fe.JSONVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value, detail, err
+}
+
+func (fe *FallbackEvaluator) JSONVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	_, detail, err := fe.JSONVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value, err
+}
+
+func (fe *FallbackEvaluator) JSONVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	detail, err := fe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: defaultVal,
+		Kind:            JSONKind,
+	})
+	return detail.Value, detail, err
+}
+
+/* * * STRING * * */
+
+func (fe *FallbackEvaluator) StringVariation(key string, ldctx ldcontext.Context, defaultVal string) (string, error) {
+	_, detail, err := fe.StringVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.StringValue(), err
+}
+
+func (fe *FallbackEvaluator) StringVariationDetail(key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	_, detail, err := fe.StringVariationDetailCtx(fe.ctx, key, ldctx, defaultVal)
+	return detail.Value.StringValue(), detail, err
+}
+
+func (fe *FallbackEvaluator) StringVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal string) (string, error) {
+	_, detail, err := fe.StringVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.StringValue(), err
+}
+
+func (fe *FallbackEvaluator) StringVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	detail, err := fe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.String(defaultVal),
+		Kind:            StringKind,
+	})
+	return detail.Value.StringValue(), detail, err
+}
@@ -0,0 +1,167 @@
+package goldhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// FlagMetadata describes a flag, as declared by a Flag definition
+type FlagMetadata struct {
+	Key         string
+	Kind        ValueKind
+	Default     ldvalue.Value
+	Description string
+}
+
+type flagMetadataKey struct{}
+
+// FlagFromContext reports the metadata of the Flag definition through which
+// the evaluation being observed was made, if it was. It is meant to be called
+// from within Observer.Observe (or the like), using the context passed to it.
+func FlagFromContext(ctx context.Context) (FlagMetadata, bool) {
+	fm, ok := ctx.Value(flagMetadataKey{}).(FlagMetadata)
+	return fm, ok
+}
+
+// Flag is the definition of a feature flag, which declares its key, the type
+// of its value, and the default to be served when it can't be evaluated, in
+// one place rather than at every callsite.
+type Flag[T any] struct {
+	Key         string
+	Default     T
+	Description string
+
+	kind      ValueKind
+	variation func(ctx context.Context, e EvaluatorCtx, key string, ldctx ldcontext.Context, defaultVal T) (T, ldreason.EvaluationDetail, error)
+	encode    func(T) ldvalue.Value
+}
+
+// BoolFlag defines a flag with a boolean value
+func BoolFlag(key string, defaultVal bool, description string) Flag[bool] {
+	return Flag[bool]{
+		Key:         key,
+		Default:     defaultVal,
+		Description: description,
+		kind:        BoolKind,
+		variation: func(ctx context.Context, e EvaluatorCtx, key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+			return e.BoolVariationDetailCtx(ctx, key, ldctx, defaultVal)
+		},
+		encode: ldvalue.Bool,
+	}
+}
+
+// Float64Flag defines a flag with a numeric value
+func Float64Flag(key string, defaultVal float64, description string) Flag[float64] {
+	return Flag[float64]{
+		Key:         key,
+		Default:     defaultVal,
+		Description: description,
+		kind:        Float64Kind,
+		variation: func(ctx context.Context, e EvaluatorCtx, key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+			return e.Float64VariationDetailCtx(ctx, key, ldctx, defaultVal)
+		},
+		encode: ldvalue.Float64,
+	}
+}
+
+// IntFlag defines a flag with an integer value
+func IntFlag(key string, defaultVal int, description string) Flag[int] {
+	return Flag[int]{
+		Key:         key,
+		Default:     defaultVal,
+		Description: description,
+		kind:        IntKind,
+		variation: func(ctx context.Context, e EvaluatorCtx, key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+			return e.IntVariationDetailCtx(ctx, key, ldctx, defaultVal)
+		},
+		encode: ldvalue.Int,
+	}
+}
+
+// StringFlag defines a flag with a string value
+func StringFlag(key string, defaultVal string, description string) Flag[string] {
+	return Flag[string]{
+		Key:         key,
+		Default:     defaultVal,
+		Description: description,
+		kind:        StringKind,
+		variation: func(ctx context.Context, e EvaluatorCtx, key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+			return e.StringVariationDetailCtx(ctx, key, ldctx, defaultVal)
+		},
+		encode: ldvalue.String,
+	}
+}
+
+// JSONFlag defines a flag with a JSON value, which is decoded into a T (as
+// per encoding/json). If the value can't be decoded, the default is served
+// along with the error.
+func JSONFlag[T any](key string, defaultVal T, description string) Flag[T] {
+	encode := func(v T) ldvalue.Value {
+		return ldvalue.CopyArbitraryValue(v)
+	}
+	return Flag[T]{
+		Key:         key,
+		Default:     defaultVal,
+		Description: description,
+		kind:        JSONKind,
+		variation: func(ctx context.Context, e EvaluatorCtx, key string, ldctx ldcontext.Context, defaultVal T) (T, ldreason.EvaluationDetail, error) {
+			value, detail, err := e.JSONVariationDetailCtx(ctx, key, ldctx, encode(defaultVal))
+			var result T
+			if jsonErr := json.Unmarshal([]byte(value.JSONString()), &result); jsonErr != nil {
+				return defaultVal, detail, fmt.Errorf("decoding flag %q: %w", key, jsonErr)
+			}
+			return result, detail, err
+		},
+		encode: encode,
+	}
+}
+
+// Metadata describes the flag
+func (f Flag[T]) Metadata() FlagMetadata {
+	fm := FlagMetadata{
+		Key:         f.Key,
+		Kind:        f.kind,
+		Description: f.Description,
+	}
+	if f.encode != nil {
+		fm.Default = f.encode(f.Default)
+	}
+	return fm
+}
+
+// Get evaluates the flag for the given context, using the Evaluator carried
+// by ctx (as per NewContext). If there is none, or it is not also an
+// EvaluatorCtx (as an ObservedEvaluator is), the default is served along with
+// an error.
+func (f Flag[T]) Get(ctx context.Context, ldctx ldcontext.Context) (T, error) {
+	result, _, err := f.GetDetail(ctx, ldctx)
+	return result, err
+}
+
+// GetDetail is as per Get, but also returns the evaluation detail
+func (f Flag[T]) GetDetail(ctx context.Context, ldctx ldcontext.Context) (T, ldreason.EvaluationDetail, error) {
+	e, _ := FromContext(ctx)
+	ec, ok := e.(EvaluatorCtx)
+	if !ok {
+		return f.Default, ldreason.NewEvaluationDetailForError(ldreason.EvalErrorClientNotReady, f.Metadata().Default),
+			fmt.Errorf("no evaluator for flag %q", f.Key)
+	}
+	return f.Evaluate(ctx, ec, ldctx)
+}
+
+// Evaluate evaluates the flag for the given context, using the given
+// EvaluatorCtx, which is handed ctx. Observers can find the flag's metadata
+// via FlagFromContext.
+func (f Flag[T]) Evaluate(ctx context.Context, e EvaluatorCtx, ldctx ldcontext.Context) (T, ldreason.EvaluationDetail, error) {
+	if f.variation == nil {
+		return f.Default, ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, ldvalue.Null()),
+			fmt.Errorf("flag %q was not declared by a constructor", f.Key)
+	}
+	ctx = context.WithValue(ctx, flagMetadataKey{}, f.Metadata())
+	return f.variation(ctx, e, f.Key, ldctx, f.Default)
+}
@@ -7,6 +7,7 @@ toolchain go1.22.1
 require (
 	github.com/launchdarkly/go-sdk-common/v3 v3.1.0
 	github.com/launchdarkly/go-server-sdk/v6 v6.1.0
+	github.com/launchdarkly/go-server-sdk/v7 v7.5.0
 )
 
 require (
@@ -21,7 +22,6 @@ require (
 	github.com/launchdarkly/go-semver v1.0.2 // indirect
 	github.com/launchdarkly/go-server-sdk-evaluation/v2 v2.0.2 // indirect
 	github.com/launchdarkly/go-server-sdk-evaluation/v3 v3.0.0 // indirect
-	github.com/launchdarkly/go-server-sdk/v7 v7.5.0 // indirect
 	github.com/mailru/easyjson v0.7.6 // indirect
 	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
 	golang.org/x/exp v0.0.0-20220823124025-807a23277127 // indirect
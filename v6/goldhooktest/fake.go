@@ -0,0 +1,283 @@
+// Package goldhooktest provides a scriptable fake of the LaunchDarkly client,
+// and an Observer which records evaluations and makes assertions about them,
+// so that observers and feature-gated code can be tested without the SDK.
+package goldhooktest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+)
+
+// Result is what a Fake serves for an evaluation.
+//
+// If Err is set, the callsite default is served along with Err, and a Reason
+// of ERROR/EXCEPTION unless another Reason is given. Otherwise Value is
+// served, with a Reason of FALLTHROUGH unless another Reason is given.
+// Latency is how long the evaluation takes.
+type Result struct {
+	Value          ldvalue.Value
+	VariationIndex ldvalue.OptionalInt
+	Reason         ldreason.EvaluationReason
+	Err            error
+	Latency        time.Duration
+}
+
+// Serve is the Result of a flag serving the given value and variation
+func Serve(value ldvalue.Value, variation int) Result {
+	return Result{
+		Value:          value,
+		VariationIndex: ldvalue.NewOptionalInt(variation),
+	}
+}
+
+// Fail is the Result of an evaluation which errs
+func Fail(err error) Result {
+	return Result{Err: err}
+}
+
+// WithReason returns a copy of the Result with the given Reason
+func (r Result) WithReason(reason ldreason.EvaluationReason) Result {
+	r.Reason = reason
+	return r
+}
+
+// WithLatency returns a copy of the Result which takes the given time
+func (r Result) WithLatency(latency time.Duration) Result {
+	r.Latency = latency
+	return r
+}
+
+// Fake implements both the Evaluator and EvaluatorCtx interfaces, serving
+// whatever it has been scripted to per flag key, and optionally per context.
+// An evaluation of a flag key which has not been scripted errs as the SDK
+// does, with FLAG_NOT_FOUND, and one which asks for a type which doesn't
+// match the scripted value errs with WRONG_TYPE.
+type Fake struct {
+	mu       sync.RWMutex
+	flags    map[string]Result
+	contexts map[string]map[string]Result
+}
+
+// NewFake has nothing scripted
+func NewFake() *Fake {
+	return &Fake{
+		flags:    map[string]Result{},
+		contexts: map[string]map[string]Result{},
+	}
+}
+
+// Set scripts the Result for evaluations of the flag key, for any context
+// not otherwise scripted
+func (f *Fake) Set(key string, r Result) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags[key] = r
+	return f
+}
+
+// SetFor scripts the Result for evaluations of the flag key, for the context
+// whose FullyQualifiedKey is given; for a context of kind "user", that is
+// just its key.
+func (f *Fake) SetFor(key, contextKey string, r Result) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.contexts[key] == nil {
+		f.contexts[key] = map[string]Result{}
+	}
+	f.contexts[key][contextKey] = r
+	return f
+}
+
+// Clear forgets everything scripted for the flag key
+func (f *Fake) Clear(key string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.flags, key)
+	delete(f.contexts, key)
+	return f
+}
+
+func (f *Fake) lookup(key string, ldctx ldcontext.Context) (Result, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if r, ok := f.contexts[key][ldctx.FullyQualifiedKey()]; ok {
+		return r, true
+	}
+	r, ok := f.flags[key]
+	return r, ok
+}
+
+func (f *Fake) evaluate(
+	ctx context.Context,
+	key string,
+	ldctx ldcontext.Context,
+	defaultVal ldvalue.Value,
+	kind goldhook.ValueKind,
+) (ldreason.EvaluationDetail, error) {
+	r, ok := f.lookup(key, ldctx)
+	if !ok {
+		return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorFlagNotFound, defaultVal),
+			fmt.Errorf("unknown flag key %q", key)
+	}
+
+	if r.Latency > 0 {
+		timer := time.NewTimer(r.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, defaultVal), ctx.Err()
+		}
+	}
+
+	if r.Err != nil {
+		detail := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, defaultVal)
+		if r.Reason.GetKind() != "" {
+			detail.Reason = r.Reason
+		}
+		return detail, r.Err
+	}
+	if !matches(r.Value, kind) {
+		return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorWrongType, defaultVal),
+			fmt.Errorf("flag %q is not of type %s", key, kind)
+	}
+	detail := ldreason.EvaluationDetail{
+		Value:          r.Value,
+		VariationIndex: r.VariationIndex,
+		Reason:         r.Reason,
+	}
+	if detail.Reason.GetKind() == "" {
+		detail.Reason = ldreason.NewEvalReasonFallthrough()
+	}
+	return detail, nil
+}
+
+func matches(v ldvalue.Value, kind goldhook.ValueKind) bool {
+	switch kind {
+	case goldhook.BoolKind:
+		return v.IsBool()
+	case goldhook.Float64Kind, goldhook.IntKind:
+		return v.IsNumber()
+	case goldhook.StringKind:
+		return v.IsString()
+	}
+	return true
+}
+
+// BoolVariation conforms to the Evaluator interface
+func (f *Fake) BoolVariation(key string, ldctx ldcontext.Context, defaultVal bool) (bool, error) {
+	return f.BoolVariationCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// BoolVariationDetail conforms to the Evaluator interface
+func (f *Fake) BoolVariationDetail(key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	return f.BoolVariationDetailCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// BoolVariationCtx conforms to the EvaluatorCtx interface
+func (f *Fake) BoolVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal bool) (bool, error) {
+	result, _, err := f.BoolVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return result, err
+}
+
+// BoolVariationDetailCtx conforms to the EvaluatorCtx interface
+func (f *Fake) BoolVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(ctx, key, ldctx, ldvalue.Bool(defaultVal), goldhook.BoolKind)
+	return detail.Value.BoolValue(), detail, err
+}
+
+// Float64Variation conforms to the Evaluator interface
+func (f *Fake) Float64Variation(key string, ldctx ldcontext.Context, defaultVal float64) (float64, error) {
+	return f.Float64VariationCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// Float64VariationDetail conforms to the Evaluator interface
+func (f *Fake) Float64VariationDetail(key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	return f.Float64VariationDetailCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// Float64VariationCtx conforms to the EvaluatorCtx interface
+func (f *Fake) Float64VariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal float64) (float64, error) {
+	result, _, err := f.Float64VariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return result, err
+}
+
+// Float64VariationDetailCtx conforms to the EvaluatorCtx interface
+func (f *Fake) Float64VariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(ctx, key, ldctx, ldvalue.Float64(defaultVal), goldhook.Float64Kind)
+	return detail.Value.Float64Value(), detail, err
+}
+
+// IntVariation conforms to the Evaluator interface
+func (f *Fake) IntVariation(key string, ldctx ldcontext.Context, defaultVal int) (int, error) {
+	return f.IntVariationCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// IntVariationDetail conforms to the Evaluator interface
+func (f *Fake) IntVariationDetail(key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	return f.IntVariationDetailCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// IntVariationCtx conforms to the EvaluatorCtx interface
+func (f *Fake) IntVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal int) (int, error) {
+	result, _, err := f.IntVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return result, err
+}
+
+// IntVariationDetailCtx conforms to the EvaluatorCtx interface
+func (f *Fake) IntVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(ctx, key, ldctx, ldvalue.Int(defaultVal), goldhook.IntKind)
+	return detail.Value.IntValue(), detail, err
+}
+
+// JSONVariation conforms to the Evaluator interface
+func (f *Fake) JSONVariation(key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	return f.JSONVariationCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// JSONVariationDetail conforms to the Evaluator interface
+func (f *Fake) JSONVariationDetail(key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	return f.JSONVariationDetailCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// JSONVariationCtx conforms to the EvaluatorCtx interface
+func (f *Fake) JSONVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	result, _, err := f.JSONVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return result, err
+}
+
+// JSONVariationDetailCtx conforms to the EvaluatorCtx interface
+func (f *Fake) JSONVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(ctx, key, ldctx, defaultVal, goldhook.JSONKind)
+	return detail.Value, detail, err
+}
+
+// StringVariation conforms to the Evaluator interface
+func (f *Fake) StringVariation(key string, ldctx ldcontext.Context, defaultVal string) (string, error) {
+	return f.StringVariationCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// StringVariationDetail conforms to the Evaluator interface
+func (f *Fake) StringVariationDetail(key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	return f.StringVariationDetailCtx(context.Background(), key, ldctx, defaultVal)
+}
+
+// StringVariationCtx conforms to the EvaluatorCtx interface
+func (f *Fake) StringVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal string) (string, error) {
+	result, _, err := f.StringVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return result, err
+}
+
+// StringVariationDetailCtx conforms to the EvaluatorCtx interface
+func (f *Fake) StringVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	detail, err := f.evaluate(ctx, key, ldctx, ldvalue.String(defaultVal), goldhook.StringKind)
+	return detail.Value.StringValue(), detail, err
+}
@@ -0,0 +1,126 @@
+package goldhooktest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+)
+
+// Evaluation is a single evaluation, as seen by a RecordingObserver
+type Evaluation struct {
+	Key             string
+	Context         ldcontext.Context
+	CallsiteDefault ldvalue.Value
+	Elapsed         time.Duration
+	Detail          ldreason.EvaluationDetail
+	Err             error
+	InterceptedBy   string
+}
+
+// Errored is true if the evaluation returned an error, or has an ERROR reason
+func (ev Evaluation) Errored() bool {
+	return ev.Err != nil || ev.Detail.Reason.GetKind() == ldreason.EvalReasonError
+}
+
+// RecordingObserver is an Observer which keeps every evaluation it sees, and
+// makes assertions about them.
+type RecordingObserver struct {
+	mu          sync.Mutex
+	evaluations []Evaluation
+}
+
+// NewRecordingObserver has seen no evaluations
+func NewRecordingObserver() *RecordingObserver {
+	return &RecordingObserver{}
+}
+
+// Observe conforms to the Observer interface
+func (ro *RecordingObserver) Observe(
+	ctx context.Context,
+	key string,
+	ldctx ldcontext.Context,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	by, _ := goldhook.InterceptedBy(ctx)
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	ro.evaluations = append(ro.evaluations, Evaluation{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: callsiteDefault,
+		Elapsed:         elapsed,
+		Detail:          detail,
+		Err:             evalErr,
+		InterceptedBy:   by,
+	})
+}
+
+// Evaluations returns those seen so far, in order
+func (ro *RecordingObserver) Evaluations() []Evaluation {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	return append([]Evaluation{}, ro.evaluations...)
+}
+
+// Of returns those evaluations seen so far of the flag key, in order
+func (ro *RecordingObserver) Of(key string) []Evaluation {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	evs := []Evaluation{}
+	for _, ev := range ro.evaluations {
+		if ev.Key == key {
+			evs = append(evs, ev)
+		}
+	}
+	return evs
+}
+
+// Reset forgets the evaluations seen so far
+func (ro *RecordingObserver) Reset() {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	ro.evaluations = nil
+}
+
+// AssertEvaluated fails the test unless the flag key was evaluated n times
+func (ro *RecordingObserver) AssertEvaluated(t testing.TB, key string, n int) {
+	t.Helper()
+	if got := len(ro.Of(key)); got != n {
+		t.Errorf("%s - expected %d evaluations; got %d\n", key, n, got)
+	}
+}
+
+// AssertReason fails the test unless the flag key was evaluated n times with
+// the given kind of reason
+func (ro *RecordingObserver) AssertReason(t testing.TB, key string, kind ldreason.EvalReasonKind, n int) {
+	t.Helper()
+	got := 0
+	for _, ev := range ro.Of(key) {
+		if ev.Detail.Reason.GetKind() == kind {
+			got++
+		}
+	}
+	if got != n {
+		t.Errorf("%s - expected %d evaluations with reason %s; got %d\n", key, n, kind, got)
+	}
+}
+
+// AssertNoErrors fails the test if any evaluation errored
+func (ro *RecordingObserver) AssertNoErrors(t testing.TB) {
+	t.Helper()
+	for _, ev := range ro.Evaluations() {
+		if ev.Errored() {
+			t.Errorf("%s - expected no error; got %v (%s)\n", ev.Key, ev.Err, ev.Detail.Reason)
+		}
+	}
+}
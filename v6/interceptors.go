@@ -0,0 +1,165 @@
+package goldhook
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// EvaluateFunc performs (or stands in for) a single flag evaluation.
+type EvaluateFunc func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error)
+
+// Interceptor wraps the call to the underlying client. It may pass the request
+// along to next unchanged, replace the result that comes back from next, or
+// not call next at all.
+type Interceptor interface {
+	// Name identifies the Interceptor to observers, when it changed a result
+	Name() string
+
+	Intercept(next EvaluateFunc) EvaluateFunc
+}
+
+// NewInterceptor adapts a middleware function into a named Interceptor
+func NewInterceptor(name string, fn func(next EvaluateFunc) EvaluateFunc) Interceptor {
+	return &interceptorFunc{name: name, fn: fn}
+}
+
+type interceptorFunc struct {
+	name string
+	fn   func(next EvaluateFunc) EvaluateFunc
+}
+
+func (ifn *interceptorFunc) Name() string {
+	return ifn.name
+}
+
+func (ifn *interceptorFunc) Intercept(next EvaluateFunc) EvaluateFunc {
+	return ifn.fn(next)
+}
+
+type interceptedByKey struct{}
+
+// InterceptedBy reports the name of the Interceptor which changed the result
+// of the evaluation being observed, if any. It is meant to be called from
+// within Observer.Observe, using the context that was passed to it.
+func InterceptedBy(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(interceptedByKey{}).(string)
+	return name, ok
+}
+
+func withInterceptedBy(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, interceptedByKey{}, name)
+}
+
+// intercept runs the request through the interceptors, outermost first, and
+// on down to the client. It also reports the name of the outermost interceptor
+// that changed the result, either by replacing it or by not calling next.
+func intercept(ctx context.Context, req EvaluationRequest, interceptors []Interceptor, base EvaluateFunc) (ldreason.EvaluationDetail, string, error) {
+	var by string
+	next := base
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, inner := interceptors[i], next
+		next = func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			called := false
+			var innerDetail ldreason.EvaluationDetail
+			var innerErr error
+			tracked := func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+				called = true
+				innerDetail, innerErr = inner(ctx, req)
+				return innerDetail, innerErr
+			}
+			detail, err := ic.Intercept(tracked)(ctx, req)
+			if !called || !sameErr(err, innerErr) || !sameDetail(detail, innerDetail) {
+				by = ic.Name()
+			}
+			return detail, err
+		}
+	}
+	detail, err := next(ctx, req)
+	return detail, by, err
+}
+
+// sameErr reports whether a is (or wraps) b, without comparing error values
+// of an uncomparable type with ==, which would panic
+func sameErr(a, b error) bool {
+	if a != nil && b != nil {
+		if t := reflect.TypeOf(a); t == reflect.TypeOf(b) && !t.Comparable() {
+			return reflect.DeepEqual(a, b)
+		}
+	}
+	return errors.Is(a, b)
+}
+
+func sameDetail(a, b ldreason.EvaluationDetail) bool {
+	return a.Value.Equal(b.Value) &&
+		a.VariationIndex == b.VariationIndex &&
+		a.Reason == b.Reason
+}
+
+func kindMatches(kind ValueKind, value ldvalue.Value) bool {
+	switch kind {
+	case BoolKind:
+		return value.Type() == ldvalue.BoolType
+	case Float64Kind, IntKind:
+		return value.IsNumber()
+	case StringKind:
+		return value.Type() == ldvalue.StringType
+	}
+	return true
+}
+
+// Override serves the given value for the given flag key, without calling the
+// underlying client. If the value is not of the type requested at the
+// callsite, the callsite default is served instead, with a WRONG_TYPE reason,
+// as the LaunchDarkly SDK itself would do.
+func Override(key string, value ldvalue.Value) Interceptor {
+	return NewInterceptor("override:"+key, func(next EvaluateFunc) EvaluateFunc {
+		return func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			if req.Key != key {
+				return next(ctx, req)
+			}
+			if !kindMatches(req.Kind, value) {
+				return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorWrongType, req.CallsiteDefault), nil
+			}
+			return ldreason.EvaluationDetail{Value: value, Reason: ldreason.NewEvalReasonOff()}, nil
+		}
+	})
+}
+
+// KillSwitch serves the callsite default for each of the given flag keys,
+// without calling the underlying client.
+func KillSwitch(keys ...string) Interceptor {
+	killed := map[string]bool{}
+	for _, k := range keys {
+		killed[k] = true
+	}
+	return NewInterceptor("killswitch", func(next EvaluateFunc) EvaluateFunc {
+		return func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			if !killed[req.Key] {
+				return next(ctx, req)
+			}
+			return ldreason.EvaluationDetail{Value: req.CallsiteDefault, Reason: ldreason.NewEvalReasonOff()}, nil
+		}
+	})
+}
+
+// DefaultOnError swallows any error from the evaluation, and serves the
+// callsite default in its place.
+func DefaultOnError() Interceptor {
+	return NewInterceptor("default-on-error", func(next EvaluateFunc) EvaluateFunc {
+		return func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			detail, err := next(ctx, req)
+			if err != nil {
+				detail.Value = req.CallsiteDefault
+				detail.VariationIndex = ldvalue.OptionalInt{}
+			}
+			return detail, nil
+		}
+	})
+}
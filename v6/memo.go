@@ -0,0 +1,111 @@
+package goldhook
+
+import (
+	"context"
+	"sync"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+)
+
+// MemoizedExtension is the extension which Memoize sets (to true) on an
+// evaluation which was served from the memo, rather than evaluated afresh.
+const MemoizedExtension = "memoized"
+
+type memoKey struct{}
+
+type memo struct {
+	mu      sync.Mutex
+	entries map[memoEntryKey][]*memoEntry
+}
+
+// memoEntryKey narrows down the entries which might match a request, each of
+// which must then match it in full
+type memoEntryKey struct {
+	key     string
+	context string
+	kind    ValueKind
+}
+
+type memoEntry struct {
+	context ldcontext.Context
+
+	ready  chan struct{}
+	detail ldreason.EvaluationDetail
+	err    error
+}
+
+func (e *memoEntry) matches(req EvaluationRequest) bool {
+	return e.context.Equal(req.Context)
+}
+
+// WithMemoization returns a copy of ctx which starts a new memoization scope
+// (e.g. for a single request or job), for the Memoize Interceptor to use.
+func WithMemoization(ctx context.Context) context.Context {
+	return context.WithValue(ctx, memoKey{}, &memo{entries: map[memoEntryKey][]*memoEntry{}})
+}
+
+// Memoize evaluates each flag at most once per memoization scope, for each
+// context (compared by all of its attributes, not only its key) and requested
+// type, so that the same flag can't change value part way through a request
+// if an update arrives. Later evaluations are served the same result, are
+// still observed, and are marked with the MemoizedExtension; if the result was
+// an error, each is served its own callsite default, with the same error and
+// reason. One which is done waiting for the first to finish serves its
+// callsite default, with the error of its context.
+// Evaluations outside of a scope started by WithMemoization are passed along
+// as usual.
+//
+// It should usually be the first (outermost) Interceptor.
+func Memoize() Interceptor {
+	return NewInterceptor("memoize", func(next EvaluateFunc) EvaluateFunc {
+		return func(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			m, ok := ctx.Value(memoKey{}).(*memo)
+			if !ok {
+				return next(ctx, req)
+			}
+			ek := memoEntryKey{key: req.Key, context: req.Context.FullyQualifiedKey(), kind: req.Kind}
+
+			m.mu.Lock()
+			var entry *memoEntry
+			for _, e := range m.entries[ek] {
+				if e.matches(req) {
+					entry = e
+					break
+				}
+			}
+			found := entry != nil
+			if !found {
+				entry = &memoEntry{context: req.Context, ready: make(chan struct{})}
+				m.entries[ek] = append(m.entries[ek], entry)
+			}
+			m.mu.Unlock()
+
+			if found {
+				select {
+				case <-entry.ready:
+				case <-ctx.Done():
+					return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, req.CallsiteDefault), ctx.Err()
+				}
+				SetExtension(ctx, MemoizedExtension, true)
+				detail := entry.detail
+				if entry.err != nil || detail.Reason.GetKind() == ldreason.EvalReasonError {
+					// the value served was the first callsite's default
+					detail.Value = req.CallsiteDefault
+				}
+				return detail, entry.err
+			}
+			evaluated := false
+			defer func() {
+				// don't leave anyone waiting on an evaluation which panicked
+				if !evaluated {
+					entry.detail = ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, req.CallsiteDefault)
+				}
+				close(entry.ready)
+			}()
+			entry.detail, entry.err = next(ctx, req)
+			evaluated = true
+			return entry.detail, entry.err
+		}
+	})
+}
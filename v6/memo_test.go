@@ -0,0 +1,155 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+func TestMemoize(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := goldhooktest.NewFake().Set("color", goldhooktest.Serve(ldvalue.String("red"), 0))
+	events := []goldhook.EvaluationEvent{}
+	recorder := goldhook.EventObserverFunc(func(_ context.Context, ev goldhook.EvaluationEvent) {
+		events = append(events, ev)
+	})
+	hooked, err := goldhook.NewEventEvaluator(context.Background(), fake, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(goldhook.Memoize())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user, other := ldcontext.New(now), ldcontext.New("other")
+	scope := goldhook.WithMemoization(context.Background())
+
+	first, _ := hooked.StringVariationCtx(scope, "color", user, "")
+	// the flag changes part way through the request
+	fake.Set("color", goldhooktest.Serve(ldvalue.String("blue"), 1))
+	second, _ := hooked.StringVariationCtx(scope, "color", user, "")
+	if first != "red" || second != "red" {
+		t.Errorf("expected %q twice; got %q and %q\n", "red", first, second)
+	}
+
+	// other contexts and types are memoized separately
+	if s, _ := hooked.StringVariationCtx(scope, "color", other, ""); s != "blue" {
+		t.Errorf("other - expected %q; got %q\n", "blue", s)
+	}
+	if v, _ := hooked.JSONVariationCtx(scope, "color", user, ldvalue.Null()); v.StringValue() != "blue" {
+		t.Errorf("json - expected %q; got %v\n", "blue", v)
+	}
+
+	// a new scope, or no scope, sees the change
+	if s, _ := hooked.StringVariationCtx(goldhook.WithMemoization(context.Background()), "color", user, ""); s != "blue" {
+		t.Errorf("new scope - expected %q; got %q\n", "blue", s)
+	}
+	if s, _ := hooked.StringVariation("color", user, ""); s != "blue" {
+		t.Errorf("no scope - expected %q; got %q\n", "blue", s)
+	}
+
+	if len(events) != 6 {
+		t.Fatalf("expected %d events; got %d\n", 6, len(events))
+	}
+	for i, ev := range events {
+		memoized := ev.Extensions[goldhook.MemoizedExtension] == true
+		if memoized != (i == 1) {
+			t.Errorf("%d - expected memoized %t; got %+v\n", i, i == 1, ev)
+		}
+		if memoized && ev.InterceptedBy != "memoize" {
+			t.Errorf("%d - expected %q; got %q\n", i, "memoize", ev.InterceptedBy)
+		}
+	}
+}
+
+func TestMemoizeMatches(t *testing.T) {
+	fake := goldhooktest.NewFake().Set("color", goldhooktest.Serve(ldvalue.String("red"), 0))
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(goldhook.Memoize())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	scope := goldhook.WithMemoization(context.Background())
+
+	user := ldcontext.New("memo")
+	hooked.StringVariationCtx(scope, "color", user, "")
+	fake.Set("color", goldhooktest.Serve(ldvalue.String("blue"), 1))
+
+	// the same key with other attributes is evaluated afresh
+	if s, _ := hooked.StringVariationCtx(scope, "color", ldcontext.NewBuilder("memo").SetString("country", "nz").Build(), ""); s != "blue" {
+		t.Errorf("attributes - expected %q; got %q\n", "blue", s)
+	}
+	// but another default is not, being no reason for the value to change
+	if s, _ := hooked.StringVariationCtx(scope, "color", user, "none"); s != "red" {
+		t.Errorf("default - expected %q; got %q\n", "red", s)
+	}
+	if s, _ := hooked.StringVariationCtx(scope, "color", user, ""); s != "red" {
+		t.Errorf("memoized - expected %q; got %q\n", "red", s)
+	}
+
+	// an error serves each callsite its own default
+	hooked.StringVariationCtx(scope, "missing", user, "first")
+	fake.Set("missing", goldhooktest.Serve(ldvalue.String("found"), 0))
+	s, detail, err := hooked.StringVariationDetailCtx(scope, "missing", user, "second")
+	if s != "second" || !detail.Value.Equal(ldvalue.String("second")) {
+		t.Errorf("error - expected %q; got %q (%v)\n", "second", s, detail.Value)
+	}
+	if err == nil || detail.Reason.GetErrorKind() != ldreason.EvalErrorFlagNotFound {
+		t.Errorf("error - expected the memoized error; got %v (%v)\n", err, detail.Reason)
+	}
+}
+
+func TestMemoizeWaiting(t *testing.T) {
+	fake := goldhooktest.NewFake().Set("color", goldhooktest.Serve(ldvalue.String("red"), 0))
+	started, gate := make(chan struct{}), make(chan struct{})
+	gated := goldhook.NewInterceptor("gated", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+		return func(ctx context.Context, req goldhook.EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			close(started)
+			<-gate
+			return next(ctx, req)
+		}
+	})
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err = hooked.WithInterceptors(goldhook.Memoize(), gated)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	scope := goldhook.WithMemoization(context.Background())
+	user := ldcontext.New("memo")
+
+	done := make(chan string)
+	go func() {
+		s, _ := hooked.StringVariationCtx(scope, "color", user, "")
+		done <- s
+	}()
+	<-started
+
+	// another evaluation gives up waiting on the first once its context is done
+	waiting, cancel := context.WithTimeout(scope, 10*time.Millisecond)
+	defer cancel()
+	s, err := hooked.StringVariationCtx(waiting, "color", user, "")
+	if s != "" || err != context.DeadlineExceeded {
+		t.Errorf("waiting - expected %q with %v; got %q with %v\n", "", context.DeadlineExceeded, s, err)
+	}
+
+	close(gate)
+	if s := <-done; s != "red" {
+		t.Errorf("first - expected %q; got %q\n", "red", s)
+	}
+}
@@ -0,0 +1,182 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ObserverPanic describes a panic that was recovered from an observer, so that
+// it did not take down the flag evaluation (or the observers after it).
+type ObserverPanic struct {
+	// Observer is the Observer, EventObserver or StagedObserver that panicked,
+	// and Index is its position amongst those the ObservedEvaluator was
+	// constructed with (or the ClientObserver of an ObservedClient, and its
+	// position amongst those). Index is -1 for a panic from elsewhere, such as
+	// the Observer behind an AsyncObserver, the ViolationHandler of a Registry
+	// (when Observer is the *Registry), or the secondary Evaluator of a
+	// ShadowEvaluator (or its DivergenceHandler).
+	Observer interface{}
+	Index    int
+
+	Key   string
+	Value interface{}
+	Stack []byte
+
+	// Quarantined is true if this panic caused the observer to be quarantined
+	Quarantined bool
+}
+
+func (op *ObserverPanic) Error() string {
+	msg := fmt.Sprintf("observer %d (%T) panicked on %q: %v", op.Index, op.Observer, op.Key, op.Value)
+	if op.Quarantined {
+		msg += "; quarantined"
+	}
+	return msg
+}
+
+// PanicHandler is notified of every panic recovered from an observer
+type PanicHandler func(p *ObserverPanic)
+
+func logPanic(p *ObserverPanic) {
+	log.Printf("goldhook: %v\n%s", p, p.Stack)
+}
+
+// handlePanic hands p to the handler, or logs it if there is none. A panic in
+// the handler itself is recovered too, and both are logged, as there is no
+// one left to hand them to.
+func handlePanic(handler PanicHandler, p *ObserverPanic) {
+	if handler == nil {
+		handler = logPanic
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("goldhook: panic handler panicked: %v", r)
+			logPanic(p)
+		}
+	}()
+	handler(p)
+}
+
+type panicHandlerKey struct{}
+
+// withPanicHandler passes the handler along to the observers, for any panics
+// they recover away from the evaluation
+func withPanicHandler(ctx context.Context, handler PanicHandler) context.Context {
+	if handler == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, panicHandlerKey{}, handler)
+}
+
+// panicHandlerFrom returns the PanicHandler of the ObservedEvaluator whose
+// evaluation ctx came from, if any
+func panicHandlerFrom(ctx context.Context) PanicHandler {
+	handler, _ := ctx.Value(panicHandlerKey{}).(PanicHandler)
+	return handler
+}
+
+// quarantine tracks the recent panics of a single observer
+type quarantine struct {
+	until atomic.Int64 // unix nanos
+
+	limit    int
+	window   time.Duration
+	cooldown time.Duration
+
+	mu     sync.Mutex
+	panics []time.Time
+}
+
+func newQuarantines(n, limit int, window, cooldown time.Duration) []*quarantine {
+	qs := make([]*quarantine, n)
+	for i := range qs {
+		qs[i] = &quarantine{
+			limit:    limit,
+			window:   window,
+			cooldown: cooldown,
+		}
+	}
+	return qs
+}
+
+func (q *quarantine) active(now time.Time) bool {
+	return now.UnixNano() < q.until.Load()
+}
+
+// record notes a panic, and reports whether it tipped the observer into quarantine
+func (q *quarantine) record(now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	recent := q.panics[:0]
+	for _, t := range q.panics {
+		if now.Sub(t) < q.window {
+			recent = append(recent, t)
+		}
+	}
+	q.panics = append(recent, now)
+	if len(q.panics) < q.limit {
+		return false
+	}
+	q.panics = q.panics[:0]
+	until := int64(math.MaxInt64)
+	if q.cooldown > 0 {
+		until = now.Add(q.cooldown).UnixNano()
+	}
+	q.until.Store(until)
+	return true
+}
+
+// recoverStage is deferred around each call to an observer
+func (oe *ObservedEvaluator) recoverStage(i int, key string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	p := &ObserverPanic{
+		Observer: identify(oe.stages[i]),
+		Index:    i,
+		Key:      key,
+		Value:    r,
+		Stack:    debug.Stack(),
+	}
+	if oe.quarantines != nil {
+		p.Quarantined = oe.quarantines[i].record(time.Now())
+	}
+	handlePanic(oe.onPanic, p)
+}
+
+// recoverRegistry is deferred around checking an evaluation against the
+// Registry, as its ViolationHandler is called synchronously
+func (oe *ObservedEvaluator) recoverRegistry(key string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	handlePanic(oe.onPanic, &ObserverPanic{
+		Observer: oe.registry,
+		Index:    -1,
+		Key:      key,
+		Value:    r,
+		Stack:    debug.Stack(),
+	})
+}
+
+func (oe *ObservedEvaluator) quarantined(i int) bool {
+	return oe.quarantines != nil && oe.quarantines[i].active(time.Now())
+}
+
+func identify(s StagedObserver) interface{} {
+	switch st := s.(type) {
+	case observerStages:
+		return st.Observer
+	case eventStages:
+		return st.EventObserver
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/lduser"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ld "github.com/launchdarkly/go-server-sdk/v7"
+
+	"github.com/nelz9999/goldhook/v6"
+)
+
+func TestObserverPanics(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	bad := goldhook.ObserverFunc(
+		func(context.Context, string, ldcontext.Context, ldvalue.Value, time.Duration, ldreason.EvaluationDetail, error) {
+			panic(now)
+		},
+	)
+	good := 0
+	counter := goldhook.ObserverFunc(
+		func(context.Context, string, ldcontext.Context, ldvalue.Value, time.Duration, ldreason.EvaluationDetail, error) {
+			good++
+		},
+	)
+
+	hooked, err := goldhook.NewEvaluator(context.Background(), client, bad, counter)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	panics := []*goldhook.ObserverPanic{}
+	hooked = hooked.
+		WithPanicHandler(func(p *goldhook.ObserverPanic) {
+			panics = append(panics, p)
+		}).
+		WithQuarantine(2, time.Minute, 0)
+
+	user := lduser.NewAnonymousUser(now)
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if result, _ := hooked.BoolVariation(key, user, true); !result {
+			t.Errorf("result - expected %t; got %t\n", true, result)
+		}
+	}
+
+	// the panicking observer does not prevent the next from being called
+	if good != 3 {
+		t.Errorf("good - expected %d; got %d\n", 3, good)
+	}
+	// the third evaluation does not reach the quarantined observer
+	if len(panics) != 2 {
+		t.Fatalf("panics - expected %d; got %d\n", 2, len(panics))
+	}
+	for i, p := range panics {
+		if p.Key != fmt.Sprintf("key-%d", i) {
+			t.Errorf("key - expected %q; got %q\n", fmt.Sprintf("key-%d", i), p.Key)
+		}
+		if p.Index != 0 || p.Value != now {
+			t.Errorf("panic - expected observer 0 with %q; got %d with %v\n", now, p.Index, p.Value)
+		}
+		if _, ok := p.Observer.(goldhook.ObserverFunc); !ok {
+			t.Errorf("observer - expected an ObserverFunc; got %T\n", p.Observer)
+		}
+		if !strings.Contains(string(p.Stack), "TestObserverPanics") {
+			t.Errorf("stack - expected the test in:\n%s\n", p.Stack)
+		}
+		if p.Quarantined != (i == 1) {
+			t.Errorf("quarantined - expected %t; got %t\n", i == 1, p.Quarantined)
+		}
+	}
+}
+
+func TestObserverPanicsHandlerPanics(t *testing.T) {
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	bad := goldhook.ObserverFunc(
+		func(context.Context, string, ldcontext.Context, ldvalue.Value, time.Duration, ldreason.EvaluationDetail, error) {
+			panic("observer")
+		},
+	)
+	hooked, err := goldhook.NewEvaluator(context.Background(), client, bad)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	handled := 0
+	hooked = hooked.WithPanicHandler(func(p *goldhook.ObserverPanic) {
+		handled++
+		panic("handler")
+	})
+
+	// neither panic escapes the evaluation
+	if result, _ := hooked.BoolVariation("key", ldcontext.New("user"), true); !result {
+		t.Errorf("result - expected %t; got %t\n", true, result)
+	}
+	if handled != 1 {
+		t.Errorf("handled - expected %d; got %d\n", 1, handled)
+	}
+}
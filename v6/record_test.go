@@ -0,0 +1,138 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ld "github.com/launchdarkly/go-server-sdk/v7"
+
+	"github.com/nelz9999/goldhook/v6"
+)
+
+type observation struct {
+	key             string
+	ldctx           ldcontext.Context
+	callsiteDefault ldvalue.Value
+	elapsed         time.Duration
+	detail          ldreason.EvaluationDetail
+	err             error
+}
+
+func observationRecorder(obs *[]observation) goldhook.Observer {
+	return goldhook.ObserverFunc(
+		func(_ context.Context, key string, ldctx ldcontext.Context, callsiteDefault ldvalue.Value, elapsed time.Duration, detail ldreason.EvaluationDetail, err error) {
+			*obs = append(*obs, observation{key, ldctx, callsiteDefault, elapsed, detail, err})
+		},
+	)
+}
+
+func TestRecordReplay(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+	path := filepath.Join(t.TempDir(), "evaluations.jsonl")
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// record across two sessions, to check the file is appended to
+	original := []observation{}
+	for session := 0; session < 2; session++ {
+		recorder, err := goldhook.OpenRecorder(path)
+		if err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+		hooked, err := goldhook.NewEvaluator(context.Background(), client, recorder, observationRecorder(&original))
+		if err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+		hooked, err = hooked.WithInterceptors(
+			goldhook.Override("forced", ldvalue.String(now)),
+			goldhook.NewInterceptor("failing", func(next goldhook.EvaluateFunc) goldhook.EvaluateFunc {
+				return func(ctx context.Context, req goldhook.EvaluationRequest) (ldreason.EvaluationDetail, error) {
+					if req.Key == "failing" {
+						return ldreason.NewEvaluationDetailForError(ldreason.EvalErrorException, req.CallsiteDefault), fmt.Errorf("failed %s", now)
+					}
+					return next(ctx, req)
+				}
+			}),
+		)
+		if err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+
+		ldctx := ldcontext.NewBuilder(now).Kind("org").Name(fmt.Sprintf("session %d", session)).Build()
+		hooked.BoolVariation("failing", ldctx, true)
+		hooked.StringVariation("forced", ldctx, "")
+		hooked.JSONVariation("json", ldctx, ldvalue.ArrayOf(ldvalue.Int(session)))
+
+		if err := recorder.Err(); err != nil {
+			t.Errorf("unexpected: %v\n", err)
+		}
+		if err := recorder.Close(); err != nil {
+			t.Errorf("unexpected: %v\n", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer f.Close()
+
+	replayed := []observation{}
+	n, err := goldhook.Replay(context.Background(), f, observationRecorder(&replayed))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if n != len(original) || len(replayed) != len(original) {
+		t.Fatalf("replayed - expected %d; got %d (%d)\n", len(original), n, len(replayed))
+	}
+	for i, o := range original {
+		r := replayed[i]
+		if r.key != o.key || r.elapsed != o.elapsed {
+			t.Errorf("%d - expected %s in %v; got %s in %v\n", i, o.key, o.elapsed, r.key, r.elapsed)
+		}
+		if !r.ldctx.Equal(o.ldctx) {
+			t.Errorf("%d - expected %v; got %v\n", i, o.ldctx, r.ldctx)
+		}
+		if !r.callsiteDefault.Equal(o.callsiteDefault) {
+			t.Errorf("%d - expected %v; got %v\n", i, o.callsiteDefault, r.callsiteDefault)
+		}
+		if !r.detail.Value.Equal(o.detail.Value) || r.detail.VariationIndex != o.detail.VariationIndex || r.detail.Reason != o.detail.Reason {
+			t.Errorf("%d - expected %+v; got %+v\n", i, o.detail, r.detail)
+		}
+		if (r.err == nil) != (o.err == nil) || (r.err != nil && r.err.Error() != o.err.Error()) {
+			t.Errorf("%d - expected %v; got %v\n", i, o.err, r.err)
+		}
+	}
+	if _, ok := replayed[0].err.(goldhook.RecordedError); !ok {
+		t.Errorf("expected a RecordedError; got %T\n", replayed[0].err)
+	}
+}
+
+func TestReplayMalformed(t *testing.T) {
+	replayed := []observation{}
+	input := `{"key":"first","context":{"kind":"user","key":"k"},"reason":{"kind":"OFF"}}` + "\n" + "{oops\n"
+	n, err := goldhook.Replay(context.Background(), strings.NewReader(input), observationRecorder(&replayed))
+	if err == nil {
+		t.Errorf("expected an error\n")
+	}
+	if n != 1 || len(replayed) != 1 || replayed[0].key != "first" {
+		t.Errorf("expected %d replayed; got %d: %+v\n", 1, n, replayed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := goldhook.Replay(ctx, strings.NewReader(input), observationRecorder(&replayed)); err != context.Canceled {
+		t.Errorf("expected %v; got %v\n", context.Canceled, err)
+	}
+}
@@ -0,0 +1,129 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+func TestRegistry(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	if _, err := goldhook.NewRegistry(nil, goldhook.FlagDeclaration{}); err == nil {
+		t.Errorf("expected an error for no key\n")
+	}
+	if _, err := goldhook.NewRegistry(nil, goldhook.FlagDeclaration{Key: "a"}, goldhook.FlagDeclaration{Key: "a"}); err == nil {
+		t.Errorf("expected an error for a duplicate key\n")
+	}
+
+	violations := []*goldhook.FlagViolation{}
+	registry, err := goldhook.NewRegistry(
+		func(_ context.Context, v *goldhook.FlagViolation) {
+			violations = append(violations, v)
+		},
+		goldhook.FlagDeclaration{Key: "checkout-v2", Owner: "payments", Kind: goldhook.BoolKind, Default: ldvalue.Bool(false)},
+		goldhook.FlagDeclaration{Key: "old-banner", Owner: "growth", Kind: goldhook.StringKind, Expires: time.Now().Add(-time.Hour)},
+		goldhook.FlagDeclaration{Key: "legacy-search", Owner: "search", Deprecated: "use search-v3"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if err := registry.Declare(goldhook.FlagDeclaration{Key: "old-banner"}); err == nil {
+		t.Errorf("expected an error for a duplicate key\n")
+	}
+	if decls := registry.Declarations(); len(decls) != 3 || decls[0].Key != "checkout-v2" {
+		t.Errorf("unexpected: %+v\n", decls)
+	}
+
+	hooked, err := goldhook.NewEvaluator(context.Background(), goldhooktest.NewFake())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked = hooked.WithRegistry(registry)
+
+	user := ldcontext.New(now)
+	hooked.BoolVariation("checkout-v2", user, false)
+	hooked.BoolVariation("checkout-v2", user, true)
+	hooked.StringVariation("checkout-v2", user, "false")
+	hooked.StringVariation("old-banner", user, "")
+	hooked.JSONVariation("legacy-search", user, ldvalue.Null())
+	hooked.IntVariation(now, user, 0)
+
+	expected := []goldhook.ViolationKind{
+		goldhook.WrongDefault,
+		goldhook.WrongKind,
+		goldhook.WrongDefault,
+		goldhook.ExpiredFlag,
+		goldhook.DeprecatedFlag,
+		goldhook.UndeclaredFlag,
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations; got %d: %v\n", len(expected), len(violations), violations)
+	}
+	for i, v := range violations {
+		if v.Kind != expected[i] {
+			t.Errorf("%d - expected %s; got %v\n", i, expected[i], v)
+		}
+		if !strings.Contains(v.Callsite, "registry_test.go:") {
+			t.Errorf("%d - expected the callsite in this file; got %q\n", i, v.Callsite)
+		}
+	}
+	if v := violations[4]; v.Declaration.Owner != "search" || !strings.Contains(v.Error(), "use search-v3") {
+		t.Errorf("unexpected: %v\n", v)
+	}
+	if v := violations[5]; v.Request.Key != now || v.Request.Kind != goldhook.IntKind {
+		t.Errorf("unexpected: %v\n", v)
+	}
+
+	// the callsite is found through Flag definitions too
+	violations = violations[:0]
+	flag := goldhook.StringFlag(now, "", "not declared")
+	flag.Evaluate(context.Background(), hooked, user)
+	if len(violations) != 1 || !strings.Contains(violations[0].Callsite, "registry_test.go:") {
+		t.Errorf("unexpected: %v\n", violations)
+	}
+}
+
+func TestRegistryOnce(t *testing.T) {
+	reported := 0
+	registry, err := goldhook.NewRegistry(func(context.Context, *goldhook.FlagViolation) {
+		reported++
+		panic("handler")
+	})
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	hooked, err := goldhook.NewEvaluator(context.Background(), goldhooktest.NewFake())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var panics []*goldhook.ObserverPanic
+	hooked = hooked.
+		WithRegistry(registry).
+		WithPanicHandler(func(p *goldhook.ObserverPanic) {
+			panics = append(panics, p)
+		})
+
+	// the same violation from the same callsite is only reported once
+	user := ldcontext.New("once")
+	for i := 0; i < 3; i++ {
+		if v, _ := hooked.BoolVariation("undeclared", user, true); !v {
+			t.Errorf("%d - expected %t; got %t\n", i, true, v)
+		}
+	}
+	if reported != 1 {
+		t.Errorf("reported - expected %d; got %d\n", 1, reported)
+	}
+	// and the handler's panic goes to the evaluator's PanicHandler
+	if len(panics) != 1 || panics[0].Observer != registry || panics[0].Key != "undeclared" {
+		t.Errorf("panics - unexpected: %+v\n", panics)
+	}
+}
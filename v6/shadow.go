@@ -0,0 +1,349 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// Divergence describes an evaluation on which the primary and secondary
+// Evaluators of a ShadowEvaluator disagreed, as to either the value or the
+// variation index.
+type Divergence struct {
+	Request   EvaluationRequest
+	Primary   EvaluationResult
+	Secondary EvaluationResult
+}
+
+// DivergenceHandler is notified of every Divergence found by a ShadowEvaluator
+type DivergenceHandler func(ctx context.Context, d Divergence)
+
+// ShadowEvaluator serves every evaluation from a primary Evaluator, and makes
+// the same evaluation of a secondary Evaluator in the background, so as to
+// compare the two, e.g. while migrating between environments or backends.
+type ShadowEvaluator struct {
+	primary      EvaluatorCtx
+	secondary    EvaluatorCtx
+	onDivergence DivergenceHandler
+	rate         float64
+	shadows      *shadows
+	onPanic      PanicHandler
+	ctx          context.Context
+}
+
+// shadows is shared by every copy of a ShadowEvaluator
+type shadows struct {
+	compared atomic.Uint64
+	skipped  atomic.Uint64
+	diverged atomic.Uint64
+
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	// mu guards closed, so that no secondary evaluation is added to wg once
+	// Wait has begun
+	mu     sync.Mutex
+	closed bool
+}
+
+// reserve takes a slot for a secondary evaluation, unless they have all been
+// taken, or Wait has been called
+func (s *shadows) reserve() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+// NewShadowEvaluator compares the given fraction of evaluations (chosen by
+// hashing the context key, as per Sample), running at most limit secondary
+// evaluations at once. Evaluations beyond the limit are skipped rather than
+// waited for, so the secondary can never hold up the primary.
+func NewShadowEvaluator(
+	ctx context.Context,
+	primary, secondary EvaluatorCtx,
+	onDivergence DivergenceHandler,
+	rate float64,
+	limit int,
+) (*ShadowEvaluator, error) {
+	if primary == nil || secondary == nil {
+		return nil, fmt.Errorf("evaluators must not be nil")
+	}
+	if onDivergence == nil {
+		return nil, fmt.Errorf("divergence handler must not be nil")
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	return &ShadowEvaluator{
+		primary:      primary,
+		secondary:    secondary,
+		onDivergence: onDivergence,
+		rate:         rate,
+		shadows:      &shadows{slots: make(chan struct{}, limit)},
+		ctx:          orBackground(ctx),
+	}, nil
+}
+
+func (se *ShadowEvaluator) WithContext(c context.Context) Evaluator {
+	cp := *se
+	cp.ctx = orBackground(c)
+	return &cp
+}
+
+// WithPanicHandler returns a copy of the ShadowEvaluator, which hands any
+// panic of a secondary evaluation (or of the DivergenceHandler) to fn, rather
+// than logging it. The secondary is the Observer of the ObserverPanic, with an
+// Index of -1.
+func (se *ShadowEvaluator) WithPanicHandler(fn PanicHandler) *ShadowEvaluator {
+	cp := *se
+	cp.onPanic = fn
+	return &cp
+}
+
+// Compared is the number of evaluations made of the secondary
+func (se *ShadowEvaluator) Compared() uint64 {
+	return se.shadows.compared.Load()
+}
+
+// Skipped is the number of sampled evaluations not made of the secondary,
+// because the limit was reached, or Wait had been called
+func (se *ShadowEvaluator) Skipped() uint64 {
+	return se.shadows.skipped.Load()
+}
+
+// Diverged is the number of evaluations on which the secondary disagreed
+func (se *ShadowEvaluator) Diverged() uint64 {
+	return se.shadows.diverged.Load()
+}
+
+// Wait waits for the secondary evaluations in progress to finish, e.g. before
+// shutting down. If ctx is done first, Wait returns its error. Once Wait has
+// been called, no more secondary evaluations are started (by any copy of the
+// ShadowEvaluator), though the primary continues to be served.
+func (se *ShadowEvaluator) Wait(ctx context.Context) error {
+	se.shadows.mu.Lock()
+	se.shadows.closed = true
+	se.shadows.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		se.shadows.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (se *ShadowEvaluator) evaluate(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+	start := time.Now()
+	detail, err := variation(ctx, se.primary, req)
+	if sampled(req.Context.FullyQualifiedKey(), se.rate) {
+		se.shadow(ctx, req, EvaluationResult{
+			Start:   start,
+			Elapsed: time.Since(start),
+			Detail:  detail,
+			Err:     err,
+		})
+	}
+	return detail, err
+}
+
+// shadow evaluates the secondary in the background, if there is a slot free
+func (se *ShadowEvaluator) shadow(ctx context.Context, req EvaluationRequest, primary EvaluationResult) {
+	if !se.shadows.reserve() {
+		se.shadows.skipped.Add(1)
+		return
+	}
+	// the secondary outlives the evaluation, so mustn't be cancelled with it
+	ctx = context.WithoutCancel(orBackground(ctx))
+	go func() {
+		defer se.shadows.wg.Done()
+		defer func() { <-se.shadows.slots }()
+		defer func() {
+			if r := recover(); r != nil {
+				handlePanic(se.onPanic, &ObserverPanic{
+					Observer: se.secondary,
+					Index:    -1,
+					Key:      req.Key,
+					Value:    r,
+					Stack:    debug.Stack(),
+				})
+			}
+		}()
+
+		start := time.Now()
+		detail, err := variation(ctx, se.secondary, req)
+		secondary := EvaluationResult{
+			Start:   start,
+			Elapsed: time.Since(start),
+			Detail:  detail,
+			Err:     err,
+		}
+		se.shadows.compared.Add(1)
+		if primary.Detail.Value.Equal(secondary.Detail.Value) &&
+			primary.Detail.VariationIndex == secondary.Detail.VariationIndex {
+			return
+		}
+		se.shadows.diverged.Add(1)
+		se.onDivergence(ctx, Divergence{
+			Request:   req,
+			Primary:   primary,
+			Secondary: secondary,
+		})
+	}()
+}
+
+/* * * BOOL * * */
+
+func (se *ShadowEvaluator) BoolVariation(key string, ldctx ldcontext.Context, defaultVal bool) (bool, error) {
+	_, detail, err := se.BoolVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.BoolValue(), err
+}
+
+func (se *ShadowEvaluator) BoolVariationDetail(key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	_, detail, err := se.BoolVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.BoolValue(), detail, err
+}
+
+func (se *ShadowEvaluator) BoolVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal bool) (bool, error) {
+	_, detail, err := se.BoolVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.BoolValue(), err
+}
+
+func (se *ShadowEvaluator) BoolVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Bool(defaultVal),
+		Kind:            BoolKind,
+	})
+	return detail.Value.BoolValue(), detail, err
+}
+
+/* * * FLOAT * * */
+
+func (se *ShadowEvaluator) Float64Variation(key string, ldctx ldcontext.Context, defaultVal float64) (float64, error) {
+	_, detail, err := se.Float64VariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.Float64Value(), err
+}
+
+func (se *ShadowEvaluator) Float64VariationDetail(key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	_, detail, err := se.Float64VariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.Float64Value(), detail, err
+}
+
+func (se *ShadowEvaluator) Float64VariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal float64) (float64, error) {
+	_, detail, err := se.Float64VariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.Float64Value(), err
+}
+
+func (se *ShadowEvaluator) Float64VariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Float64(defaultVal),
+		Kind:            Float64Kind,
+	})
+	return detail.Value.Float64Value(), detail, err
+}
+
+/* * * INT * * */
+
+func (se *ShadowEvaluator) IntVariation(key string, ldctx ldcontext.Context, defaultVal int) (int, error) {
+	_, detail, err := se.IntVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.IntValue(), err
+}
+
+func (se *ShadowEvaluator) IntVariationDetail(key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	_, detail, err := se.IntVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.IntValue(), detail, err
+}
+
+func (se *ShadowEvaluator) IntVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal int) (int, error) {
+	_, detail, err := se.IntVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.IntValue(), err
+}
+
+func (se *ShadowEvaluator) IntVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Int(defaultVal),
+		Kind:            IntKind,
+	})
+	return detail.Value.IntValue(), detail, err
+}
+
+/* * * JSON * * */
+
+func (se *ShadowEvaluator) JSONVariation(key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	_, detail, err := se.JSONVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value, err
+}
+
+func (se *ShadowEvaluator) JSONVariationDetail(key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	_, detail, err := se.JSONVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value, detail, err
+}
+
+func (se *ShadowEvaluator) JSONVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	_, detail, err := se.JSONVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value, err
+}
+
+func (se *ShadowEvaluator) JSONVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: defaultVal,
+		Kind:            JSONKind,
+	})
+	return detail.Value, detail, err
+}
+
+/* * * STRING * * */
+
+func (se *ShadowEvaluator) StringVariation(key string, ldctx ldcontext.Context, defaultVal string) (string, error) {
+	_, detail, err := se.StringVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.StringValue(), err
+}
+
+func (se *ShadowEvaluator) StringVariationDetail(key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	_, detail, err := se.StringVariationDetailCtx(se.ctx, key, ldctx, defaultVal)
+	return detail.Value.StringValue(), detail, err
+}
+
+func (se *ShadowEvaluator) StringVariationCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal string) (string, error) {
+	_, detail, err := se.StringVariationDetailCtx(ctx, key, ldctx, defaultVal)
+	return detail.Value.StringValue(), err
+}
+
+func (se *ShadowEvaluator) StringVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
+	detail, err := se.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.String(defaultVal),
+		Kind:            StringKind,
+	})
+	return detail.Value.StringValue(), detail, err
+}
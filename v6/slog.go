@@ -0,0 +1,114 @@
+package goldhook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// SlogObserver logs each evaluation as a structured record. Evaluations which
+// erred are logged at Warn, those which fell back to the callsite default at
+// Info, and successful ones at Debug, unless configured otherwise.
+type SlogObserver struct {
+	logger *slog.Logger
+
+	errorLevel    slog.Level
+	fallbackLevel slog.Level
+	successLevel  slog.Level
+	successRate   float64
+
+	contextAttrs func(ctx context.Context) []slog.Attr
+}
+
+// NewSlogObserver logs to the given logger, or to slog.Default if it is nil
+func NewSlogObserver(logger *slog.Logger) *SlogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogObserver{
+		logger:        logger,
+		errorLevel:    slog.LevelWarn,
+		fallbackLevel: slog.LevelInfo,
+		successLevel:  slog.LevelDebug,
+		successRate:   1,
+	}
+}
+
+// WithLevels returns a copy of the SlogObserver which logs at the given levels
+func (so *SlogObserver) WithLevels(errorLevel, fallbackLevel, successLevel slog.Level) *SlogObserver {
+	cp := *so
+	cp.errorLevel = errorLevel
+	cp.fallbackLevel = fallbackLevel
+	cp.successLevel = successLevel
+	return &cp
+}
+
+// WithSuccessSampling returns a copy of the SlogObserver which logs only the
+// given fraction of successful evaluations, chosen by context key as per Sample.
+// Errors and fallbacks are always logged.
+func (so *SlogObserver) WithSuccessSampling(rate float64) *SlogObserver {
+	cp := *so
+	cp.successRate = rate
+	return &cp
+}
+
+// WithContextAttrs returns a copy of the SlogObserver which adds whatever
+// attributes fn pulls out of the context passed to Observe (e.g. trace or
+// request IDs) to each record.
+func (so *SlogObserver) WithContextAttrs(fn func(ctx context.Context) []slog.Attr) *SlogObserver {
+	cp := *so
+	cp.contextAttrs = fn
+	return &cp
+}
+
+// Observe conforms to the Observer interface
+func (so *SlogObserver) Observe(
+	ctx context.Context,
+	key string,
+	ldctx ldcontext.Context,
+	callsiteDefault ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	level := so.successLevel
+	switch {
+	case evalErr != nil || detail.Reason.GetKind() == ldreason.EvalReasonError:
+		level = so.errorLevel
+	case detail.IsDefaultValue():
+		level = so.fallbackLevel
+	default:
+		if !sampled(ldctx.FullyQualifiedKey(), so.successRate) {
+			return
+		}
+	}
+	if !so.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("flag", key),
+		slog.String("context_kind", string(ldctx.Kind())),
+		slog.String("context_key", ldctx.Key()),
+		slog.String("value", detail.Value.JSONString()),
+		slog.String("default", callsiteDefault.JSONString()),
+	}
+	if idx, ok := detail.VariationIndex.Get(); ok {
+		attrs = append(attrs, slog.Int("variation", idx))
+	}
+	attrs = append(attrs,
+		slog.String("reason", detail.Reason.String()),
+		slog.Duration("elapsed", elapsed),
+	)
+	if evalErr != nil {
+		attrs = append(attrs, slog.String("error", evalErr.Error()))
+	}
+	if so.contextAttrs != nil {
+		attrs = append(attrs, so.contextAttrs(ctx)...)
+	}
+	so.logger.LogAttrs(ctx, level, "flag evaluation", attrs...)
+}
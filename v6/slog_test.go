@@ -0,0 +1,103 @@
+package goldhook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+)
+
+func TestSlogObserver(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	observer := goldhook.NewSlogObserver(logger).
+		WithContextAttrs(func(ctx context.Context) []slog.Attr {
+			if id, ok := ctx.Value(ctxKey("trace")).(string); ok {
+				return []slog.Attr{slog.String("trace_id", id)}
+			}
+			return nil
+		})
+
+	ctx := context.WithValue(context.Background(), ctxKey("trace"), "abc123")
+	ldctx := ldcontext.NewWithKind("org", "acme")
+
+	success := ldreason.NewEvaluationDetail(ldvalue.String("on"), 1, ldreason.NewEvalReasonFallthrough())
+	fallback := ldreason.EvaluationDetail{Value: ldvalue.String("off"), Reason: ldreason.NewEvalReasonOff()}
+	notFound := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorFlagNotFound, ldvalue.String("off"))
+
+	observer.Observe(ctx, "success", ldctx, ldvalue.String("off"), time.Millisecond, success, nil)
+	observer.Observe(ctx, "fallback", ldctx, ldvalue.String("off"), time.Millisecond, fallback, nil)
+	observer.Observe(ctx, "failure", ldctx, ldvalue.String("off"), time.Millisecond, notFound, errors.New("not found"))
+
+	records := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		record := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("unexpected: %v\n", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records - expected %d; got %d\n", 3, len(records))
+	}
+
+	expected := []struct {
+		flag  string
+		level string
+	}{
+		{"success", "DEBUG"},
+		{"fallback", "INFO"},
+		{"failure", "WARN"},
+	}
+	for i, exp := range expected {
+		rec := records[i]
+		if rec["flag"] != exp.flag || rec["level"] != exp.level {
+			t.Errorf("record - expected %s at %s; got %v\n", exp.flag, exp.level, rec)
+		}
+		if rec["context_kind"] != "org" || rec["context_key"] != "acme" || rec["trace_id"] != "abc123" {
+			t.Errorf("attributes - unexpected: %v\n", rec)
+		}
+	}
+	if records[0]["variation"] != float64(1) || records[0]["value"] != `"on"` || records[0]["reason"] != "FALLTHROUGH" {
+		t.Errorf("success - unexpected: %v\n", records[0])
+	}
+	if _, ok := records[1]["variation"]; ok {
+		t.Errorf("fallback - expected no variation: %v\n", records[1])
+	}
+	if records[2]["error"] != "not found" || records[2]["reason"] != "ERROR(FLAG_NOT_FOUND)" {
+		t.Errorf("failure - unexpected: %v\n", records[2])
+	}
+}
+
+func TestSlogObserverLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	observer := goldhook.NewSlogObserver(logger).
+		WithLevels(slog.LevelError, slog.LevelDebug, slog.LevelInfo).
+		WithSuccessSampling(0)
+
+	ldctx := ldcontext.New("user")
+	success := ldreason.NewEvaluationDetail(ldvalue.Bool(true), 0, ldreason.NewEvalReasonFallthrough())
+	fallback := ldreason.EvaluationDetail{Value: ldvalue.Bool(false), Reason: ldreason.NewEvalReasonOff()}
+	notFound := ldreason.NewEvaluationDetailForError(ldreason.EvalErrorFlagNotFound, ldvalue.Bool(false))
+
+	// successes are sampled out, and fallbacks are below the handler's level
+	observer.Observe(context.Background(), "success", ldctx, ldvalue.Bool(false), 0, success, nil)
+	observer.Observe(context.Background(), "fallback", ldctx, ldvalue.Bool(false), 0, fallback, nil)
+	observer.Observe(context.Background(), "failure", ldctx, ldvalue.Bool(false), 0, notFound, nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "level=ERROR") || !strings.Contains(lines[0], "flag=failure") {
+		t.Errorf("lines - unexpected: %q\n", lines)
+	}
+}
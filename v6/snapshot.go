@@ -0,0 +1,203 @@
+package goldhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// Snapshot is a local JSON file of flag values, keyed by flag, which serves
+// as a FlagSource of last-known-good values, and is kept up to date by a
+// FallbackEvaluator (via WithSnapshot). The values are held per flag rather
+// than per context, so it suits flags which serve most contexts alike.
+//
+// A flag recorded with more than one distinct value after the file is opened
+// (because it varies by context, or has changed since) is dropped from the
+// Snapshot until it is next opened, so that one context's value is never served
+// to another.
+type Snapshot struct {
+	path string
+
+	mu     sync.Mutex
+	values map[string]ldvalue.Value
+	dirty  bool
+	err    error
+
+	// seen is the first value recorded for each flag since the file was
+	// opened, and varied the flags since recorded with another
+	seen   map[string]ldvalue.Value
+	varied map[string]bool
+
+	// writing serialises writes of the file
+	writing sync.Mutex
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenSnapshot loads the file at path, if there is one, and writes it back
+// every interval while there are new values to write (or only on Flush and
+// Close, if interval is not positive). It is the caller's responsibility to
+// Close it.
+func OpenSnapshot(path string, interval time.Duration) (*Snapshot, error) {
+	s := &Snapshot{
+		path:   path,
+		values: map[string]ldvalue.Value{},
+		seen:   map[string]ldvalue.Value{},
+		varied: map[string]bool{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &s.values); err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", path, err)
+		}
+	}
+	go s.refresh(interval)
+	return s, nil
+}
+
+// Record sets the value of the flag, to be written at the next refresh,
+// unless the flag has been recorded with another value since the file was
+// opened, in which case it is dropped instead
+func (s *Snapshot) Record(key string, value ldvalue.Value) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.varied[key] {
+		return
+	}
+	if first, ok := s.seen[key]; ok && !first.Equal(value) {
+		s.varied[key] = true
+		if _, ok := s.values[key]; ok {
+			delete(s.values, key)
+			s.dirty = true
+		}
+		return
+	}
+	s.seen[key] = value
+	if old, ok := s.values[key]; ok && old.Equal(value) {
+		return
+	}
+	s.values[key] = value
+	s.dirty = true
+}
+
+// Lookup returns the value of the flag, if there is one
+func (s *Snapshot) Lookup(key string) (ldvalue.Value, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Source makes a FlagSource of the Snapshot, which serves its values as
+// StaticSource does
+func (s *Snapshot) Source(name string) FlagSource {
+	return FlagSource{
+		Name: name,
+		Evaluate: func(_ context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+			value, ok := s.Lookup(req.Key)
+			return serveValue(req, value, ok), nil
+		},
+	}
+}
+
+// Flush writes the file now, if there are new values to write. The file is
+// replaced whole, so that a reader never sees it half written.
+func (s *Snapshot) Flush() error {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(s.values, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err == nil {
+		err = s.write(data)
+	}
+	if err != nil {
+		// try again next time
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return fmt.Errorf("snapshot %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *Snapshot) write(data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// Err returns the first error encountered while refreshing the file in the
+// background, if any
+func (s *Snapshot) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close stops the refreshing, and writes the file one last time
+func (s *Snapshot) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+	return s.Flush()
+}
+
+func (s *Snapshot) refresh(interval time.Duration) {
+	defer close(s.done)
+	if interval <= 0 {
+		<-s.stop
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				s.mu.Lock()
+				if s.err == nil {
+					s.err = err
+				}
+				s.mu.Unlock()
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package goldhook
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+)
+
+// DefaultLatencyBounds are the upper bounds of the latency histogram buckets
+// used when none are given.
+var DefaultLatencyBounds = []time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// Histogram counts durations into buckets. Counts[i] is the number of
+// durations no greater than Bounds[i] (and greater than the bound before it);
+// the final entry of Counts is for those greater than every bound.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Sum    time.Duration
+}
+
+func newHistogram(bounds []time.Duration) Histogram {
+	return Histogram{
+		Bounds: bounds,
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// latencyBounds sorts a copy of the given bounds, dropping duplicates, which
+// would otherwise make buckets that can never be counted into. Without any
+// bounds, it is the DefaultLatencyBounds.
+func latencyBounds(bounds []time.Duration) []time.Duration {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBounds
+	}
+	sorted := append([]time.Duration{}, bounds...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	unique := sorted[:0]
+	for i, b := range sorted {
+		if i == 0 || b != sorted[i-1] {
+			unique = append(unique, b)
+		}
+	}
+	return unique
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i := sort.Search(len(h.Bounds), func(i int) bool {
+		return d <= h.Bounds[i]
+	})
+	h.Counts[i]++
+	h.Sum += d
+}
+
+// Count is the total number of durations in the Histogram
+func (h Histogram) Count() uint64 {
+	var n uint64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+// FlagStats are the statistics gathered for a single flag key
+type FlagStats struct {
+	Key         string
+	Evaluations uint64
+
+	// Variations counts by variation index. Evaluations which served the
+	// callsite default have no variation index, and are counted as Fallbacks.
+	Variations map[int]uint64
+	Fallbacks  uint64
+
+	Reasons map[ldreason.EvalReasonKind]uint64
+
+	// Errors counts evaluations with an ERROR reason, or which returned an
+	// error, by the kind of error in the reason (if any)
+	Errors map[ldreason.EvalErrorKind]uint64
+
+	Latency Histogram
+}
+
+func (fs *FlagStats) clone() FlagStats {
+	cp := *fs
+	cp.Variations = make(map[int]uint64, len(fs.Variations))
+	for k, v := range fs.Variations {
+		cp.Variations[k] = v
+	}
+	cp.Reasons = make(map[ldreason.EvalReasonKind]uint64, len(fs.Reasons))
+	for k, v := range fs.Reasons {
+		cp.Reasons[k] = v
+	}
+	cp.Errors = make(map[ldreason.EvalErrorKind]uint64, len(fs.Errors))
+	for k, v := range fs.Errors {
+		cp.Errors[k] = v
+	}
+	cp.Latency.Counts = append([]uint64{}, fs.Latency.Counts...)
+	return cp
+}
+
+const statsShards = 16
+
+// StatsObserver keeps live, per-flag statistics of the evaluations it
+// observes. Flag keys are spread across shards, so that evaluations of
+// different flags seldom contend with one another.
+type StatsObserver struct {
+	bounds []time.Duration
+	shards [statsShards]statsShard
+}
+
+type statsShard struct {
+	mu    sync.Mutex
+	flags map[string]*FlagStats
+}
+
+// NewStatsObserver uses the given latency histogram bounds (in any order,
+// with any duplicates dropped), or the DefaultLatencyBounds if there are none.
+func NewStatsObserver(bounds ...time.Duration) *StatsObserver {
+	so := &StatsObserver{bounds: latencyBounds(bounds)}
+	for i := range so.shards {
+		so.shards[i].flags = map[string]*FlagStats{}
+	}
+	return so
+}
+
+// Observe conforms to the Observer interface
+func (so *StatsObserver) Observe(
+	_ context.Context,
+	key string,
+	_ ldcontext.Context,
+	_ ldvalue.Value,
+	elapsed time.Duration,
+	detail ldreason.EvaluationDetail,
+	evalErr error,
+) {
+	shard := so.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	fs, ok := shard.flags[key]
+	if !ok {
+		fs = &FlagStats{
+			Key:        key,
+			Variations: map[int]uint64{},
+			Reasons:    map[ldreason.EvalReasonKind]uint64{},
+			Errors:     map[ldreason.EvalErrorKind]uint64{},
+			Latency:    newHistogram(so.bounds),
+		}
+		shard.flags[key] = fs
+	}
+
+	fs.Evaluations++
+	if idx, ok := detail.VariationIndex.Get(); ok {
+		fs.Variations[idx]++
+	} else {
+		fs.Fallbacks++
+	}
+	fs.Reasons[detail.Reason.GetKind()]++
+	if detail.Reason.GetKind() == ldreason.EvalReasonError || evalErr != nil {
+		fs.Errors[detail.Reason.GetErrorKind()]++
+	}
+	fs.Latency.observe(elapsed)
+}
+
+// Snapshot returns a point-in-time copy of the statistics, by flag key
+func (so *StatsObserver) Snapshot() map[string]FlagStats {
+	snap := map[string]FlagStats{}
+	for i := range so.shards {
+		shard := &so.shards[i]
+		shard.mu.Lock()
+		for k, fs := range shard.flags {
+			snap[k] = fs.clone()
+		}
+		shard.mu.Unlock()
+	}
+	return snap
+}
+
+// Reset discards all the statistics gathered so far
+func (so *StatsObserver) Reset() {
+	for i := range so.shards {
+		shard := &so.shards[i]
+		shard.mu.Lock()
+		shard.flags = map[string]*FlagStats{}
+		shard.mu.Unlock()
+	}
+}
+
+func (so *StatsObserver) shard(key string) *statsShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &so.shards[h.Sum32()%statsShards]
+}
@@ -0,0 +1,165 @@
+package goldhook_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+
+	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+)
+
+func TestStreamHandler(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	if _, err := goldhook.NewStreamHandler(0, time.Second); err == nil {
+		t.Errorf("expected an error for no buffer\n")
+	}
+	if _, err := goldhook.NewStreamHandler(10, 0); err == nil {
+		t.Errorf("expected an error for no heartbeat\n")
+	}
+	stream, err := goldhook.NewStreamHandler(10, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	fake := goldhooktest.NewFake().Set("enabled", goldhooktest.Serve(ldvalue.Bool(true), 1))
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake, stream)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// nobody is listening yet
+	hooked.BoolVariation("enabled", ldcontext.New(now), false)
+
+	resp, err := http.Get(server.URL + "?context=" + now + "&reason=fallthrough")
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected an event stream; got %q\n", ct)
+	}
+	deadline := time.Now().Add(time.Second)
+	for stream.Subscribers() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	hooked.BoolVariation("enabled", ldcontext.New("other"), false)
+	hooked.BoolVariation("missing", ldcontext.New(now), false)
+	hooked.BoolVariation("enabled", ldcontext.New(now), false)
+
+	scanner := bufio.NewScanner(resp.Body)
+	events := []string{}
+	for scanner.Scan() && len(events) < 2 {
+		if line := scanner.Text(); line != "" {
+			events = append(events, line)
+		}
+	}
+	if len(events) != 2 || events[0] != "event: evaluation" || !strings.HasPrefix(events[1], "data: ") {
+		t.Fatalf("unexpected: %q\n", events)
+	}
+	var ev struct {
+		Key        string
+		ContextKey string
+		Value      bool
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(events[1], "data: ")), &ev); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if ev.Key != "enabled" || ev.ContextKey != now || !ev.Value {
+		t.Errorf("unexpected: %+v\n", ev)
+	}
+
+	// closing ends the stream
+	stream.Close()
+	for scanner.Scan() {
+	}
+	deadline = time.Now().Add(time.Second)
+	for stream.Subscribers() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := stream.Subscribers(); n != 0 {
+		t.Errorf("expected %d; got %d\n", 0, n)
+	}
+	refused, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer refused.Body.Close()
+	if refused.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected %d; got %d\n", http.StatusServiceUnavailable, refused.StatusCode)
+	}
+}
+
+func TestStreamHandlerDropped(t *testing.T) {
+	stream, err := goldhook.NewStreamHandler(1, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer stream.Close()
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	fake := goldhooktest.NewFake().Set("enabled", goldhooktest.Serve(ldvalue.Bool(true), 1))
+	hooked, err := goldhook.NewEvaluator(context.Background(), fake, stream)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer resp.Body.Close()
+	deadline := time.Now().Add(time.Second)
+	for stream.Subscribers() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		hooked.BoolVariation("enabled", ldcontext.New("dropped"), false)
+	}
+
+	// every evaluation is either sent or counted as dropped
+	sent, dropped := 0, 0
+	event := ""
+	scanner := bufio.NewScanner(resp.Body)
+	for sent+dropped < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+			if event == "evaluation" {
+				sent++
+			}
+		case event == "dropped" && strings.HasPrefix(line, "data: "):
+			var count int
+			if _, err := fmt.Sscan(strings.TrimPrefix(line, "data: "), &count); err != nil {
+				t.Fatalf("unexpected: %v\n", err)
+			}
+			dropped += count
+		}
+	}
+	if sent+dropped != n {
+		t.Errorf("expected %d accounted for; got %d sent and %d dropped\n", n, sent, dropped)
+	}
+}
@@ -11,49 +11,186 @@ import (
 )
 
 type ObservedEvaluator struct {
-	client EvaluatorCtx
-	hooks  []Observer
-	ctx    context.Context
+	client       EvaluatorCtx
+	stages       []StagedObserver
+	interceptors []Interceptor
+	onPanic      PanicHandler
+	quarantines  []*quarantine
+	registry     *Registry
+	ctx          context.Context
 }
 
 func NewEvaluator(ctx context.Context, client EvaluatorCtx, subscribers ...Observer) (*ObservedEvaluator, error) {
+	stages := make([]StagedObserver, len(subscribers))
+	for i, h := range subscribers {
+		if h == nil {
+			return nil, fmt.Errorf("subscribers must not be nil")
+		}
+		stages[i] = AsStaged(h)
+	}
+	return NewStagedEvaluator(ctx, client, stages...)
+}
+
+func NewEventEvaluator(ctx context.Context, client EvaluatorCtx, observers ...EventObserver) (*ObservedEvaluator, error) {
+	stages := make([]StagedObserver, len(observers))
+	for i, eo := range observers {
+		if eo == nil {
+			return nil, fmt.Errorf("observers must not be nil")
+		}
+		stages[i] = StagedEvents(eo)
+	}
+	return NewStagedEvaluator(ctx, client, stages...)
+}
+
+func NewStagedEvaluator(ctx context.Context, client EvaluatorCtx, stages ...StagedObserver) (*ObservedEvaluator, error) {
 	if client == nil {
 		return nil, fmt.Errorf("client must not be nil")
 	}
-	// we don't check that there are 1+ subscribers vis Postel's Law
-	// but we do check for nil subscribers which could cause later panics
-	for _, h := range subscribers {
-		if h == nil {
-			return nil, fmt.Errorf("subscribers must not be nil")
+	// we don't check that there are 1+ stages vis Postel's Law
+	// but we do check for nil stages which could cause later panics
+	for _, s := range stages {
+		if s == nil {
+			return nil, fmt.Errorf("stages must not be nil")
 		}
 	}
 	return &ObservedEvaluator{
 		client: client,
-		hooks:  subscribers,
-		ctx:    ctx,
+		stages: stages,
+		ctx:    orBackground(ctx),
 	}, nil
 }
 
 func (oe *ObservedEvaluator) WithContext(c context.Context) Evaluator {
-	return &ObservedEvaluator{
-		client: oe.client,
-		hooks:  oe.hooks,
-		ctx:    c,
+	cp := *oe
+	cp.ctx = orBackground(c)
+	return &cp
+}
+
+// orBackground stands context.Background in for a nil ctx, which would
+// otherwise panic once an evaluation derives a context from it
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
 	}
+	return ctx
 }
 
-func (oe *ObservedEvaluator) notifyHooks(
-	ctx context.Context,
-	key string,
-	ldctx ldcontext.Context,
-	callsiteDefault ldvalue.Value,
-	elapsed time.Duration,
-	detail ldreason.EvaluationDetail,
-	evalErr error,
-) {
-	for _, h := range oe.hooks {
-		h.Observe(ctx, key, ldctx, callsiteDefault, elapsed, detail, evalErr)
+// WithInterceptors returns a copy of the ObservedEvaluator which runs every
+// evaluation through the given interceptors (in addition to any it already
+// had), outermost first, before it reaches the underlying client.
+func (oe *ObservedEvaluator) WithInterceptors(interceptors ...Interceptor) (*ObservedEvaluator, error) {
+	for _, ic := range interceptors {
+		if ic == nil {
+			return nil, fmt.Errorf("interceptors must not be nil")
+		}
 	}
+	cp := *oe
+	cp.interceptors = append(append([]Interceptor{}, oe.interceptors...), interceptors...)
+	return &cp, nil
+}
+
+// WithPanicHandler returns a copy of the ObservedEvaluator which reports any
+// panic recovered from its observers to the given handler, rather than to the
+// standard logger.
+func (oe *ObservedEvaluator) WithPanicHandler(fn PanicHandler) *ObservedEvaluator {
+	cp := *oe
+	cp.onPanic = fn
+	return &cp
+}
+
+// WithQuarantine returns a copy of the ObservedEvaluator which stops calling
+// any observer that panics limit times within window, for the cooldown that
+// follows (or for good, if cooldown is not positive).
+func (oe *ObservedEvaluator) WithQuarantine(limit int, window, cooldown time.Duration) *ObservedEvaluator {
+	cp := *oe
+	cp.quarantines = newQuarantines(len(oe.stages), limit, window, cooldown)
+	return &cp
+}
+
+// WithRegistry returns a copy of the ObservedEvaluator which checks every
+// evaluation against the declarations in the Registry, before it is made.
+func (oe *ObservedEvaluator) WithRegistry(r *Registry) *ObservedEvaluator {
+	cp := *oe
+	cp.registry = r
+	return &cp
+}
+
+func (oe *ObservedEvaluator) evaluate(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+	return oe.evaluateWith(ctx, req, oe.variation)
+}
+
+// evaluateWith runs the request through the Registry, the stages and the
+// interceptors, with base in place of the client beneath them
+func (oe *ObservedEvaluator) evaluateWith(ctx context.Context, req EvaluationRequest, base EvaluateFunc) (ldreason.EvaluationDetail, error) {
+	ctx, ext := withExtensions(orBackground(ctx))
+	ctx = withPanicHandler(ctx, oe.onPanic)
+	if oe.registry != nil {
+		oe.check(ctx, req)
+	}
+	data := make([]SeriesData, len(oe.stages))
+	for i := range oe.stages {
+		data[i] = oe.before(ctx, i, req)
+	}
+	start := time.Now()
+	detail, by, err := intercept(ctx, req, oe.interceptors, base)
+	result := EvaluationResult{
+		Start:         start,
+		Elapsed:       time.Since(start),
+		Detail:        detail,
+		Err:           err,
+		InterceptedBy: by,
+		Extensions:    ext.snapshot(),
+	}
+	for i := range oe.stages {
+		oe.after(ctx, i, req, data[i], result)
+	}
+	return detail, err
+}
+
+func (oe *ObservedEvaluator) check(ctx context.Context, req EvaluationRequest) {
+	defer oe.recoverRegistry(req.Key)
+	oe.registry.check(ctx, req, time.Now())
+}
+
+func (oe *ObservedEvaluator) before(ctx context.Context, i int, req EvaluationRequest) SeriesData {
+	if oe.quarantined(i) {
+		return nil
+	}
+	defer oe.recoverStage(i, req.Key)
+	return oe.stages[i].BeforeEvaluation(ctx, req)
+}
+
+func (oe *ObservedEvaluator) after(ctx context.Context, i int, req EvaluationRequest, data SeriesData, result EvaluationResult) {
+	if oe.quarantined(i) {
+		return
+	}
+	defer oe.recoverStage(i, req.Key)
+	oe.stages[i].AfterEvaluation(ctx, req, data, result)
+}
+
+func (oe *ObservedEvaluator) variation(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+	return variation(ctx, oe.client, req)
+}
+
+// variation makes the request of the EvaluatorCtx, via the
+// *VariationDetailCtx method for its kind
+func variation(ctx context.Context, e EvaluatorCtx, req EvaluationRequest) (detail ldreason.EvaluationDetail, err error) {
+	switch req.Kind {
+	case BoolKind:
+		_, detail, err = e.BoolVariationDetailCtx(ctx, req.Key, req.Context, req.CallsiteDefault.BoolValue())
+	case Float64Kind:
+		_, detail, err = e.Float64VariationDetailCtx(ctx, req.Key, req.Context, req.CallsiteDefault.Float64Value())
+	case IntKind:
+		_, detail, err = e.IntVariationDetailCtx(ctx, req.Key, req.Context, req.CallsiteDefault.IntValue())
+	case JSONKind:
+		_, detail, err = e.JSONVariationDetailCtx(ctx, req.Key, req.Context, req.CallsiteDefault)
+	case StringKind:
+		_, detail, err = e.StringVariationDetailCtx(ctx, req.Key, req.Context, req.CallsiteDefault.StringValue())
+	default:
+		err = fmt.Errorf("unknown value kind %q", req.Kind)
+		detail = ldreason.NewEvaluationDetailForError(ldreason.EvalErrorWrongType, req.CallsiteDefault)
+	}
+	return detail, err
 }
 
 /* * * BOOL * * */
@@ -74,9 +211,12 @@ func (oe *ObservedEvaluator) BoolVariationCtx(ctx context.Context, key string, l
 }
 
 func (oe *ObservedEvaluator) BoolVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.BoolVariationDetailCtx(ctx, key, ldctx, defaultVal)
-	oe.notifyHooks(ctx, key, ldctx, ldvalue.Bool(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Bool(defaultVal),
+		Kind:            BoolKind,
+	})
 	return detail.Value.BoolValue(), detail, err
 }
 
@@ -98,9 +238,12 @@ func (oe *ObservedEvaluator) Float64VariationCtx(ctx context.Context, key string
 }
 
 func (oe *ObservedEvaluator) Float64VariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.Float64VariationDetailCtx(ctx, key, ldctx, defaultVal)
-	oe.notifyHooks(ctx, key, ldctx, ldvalue.Float64(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Float64(defaultVal),
+		Kind:            Float64Kind,
+	})
 	return detail.Value.Float64Value(), detail, err
 }
 
@@ -122,9 +265,12 @@ func (oe *ObservedEvaluator) IntVariationCtx(ctx context.Context, key string, ld
 }
 
 func (oe *ObservedEvaluator) IntVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal int) (int, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.IntVariationDetailCtx(ctx, key, ldctx, defaultVal)
-	oe.notifyHooks(ctx, key, ldctx, ldvalue.Int(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.Int(defaultVal),
+		Kind:            IntKind,
+	})
 	return detail.Value.IntValue(), detail, err
 }
 
@@ -146,9 +292,12 @@ func (oe *ObservedEvaluator) JSONVariationCtx(ctx context.Context, key string, l
 }
 
 func (oe *ObservedEvaluator) JSONVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.JSONVariationDetailCtx(ctx, key, ldctx, defaultVal)
-	oe.notifyHooks(ctx, key, ldctx, defaultVal, time.Since(start), detail, err)
+	detail, err := oe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: defaultVal,
+		Kind:            JSONKind,
+	})
 	return detail.Value, detail, err
 }
 
@@ -170,8 +319,11 @@ func (oe *ObservedEvaluator) StringVariationCtx(ctx context.Context, key string,
 }
 
 func (oe *ObservedEvaluator) StringVariationDetailCtx(ctx context.Context, key string, ldctx ldcontext.Context, defaultVal string) (string, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.StringVariationDetailCtx(ctx, key, ldctx, defaultVal)
-	oe.notifyHooks(ctx, key, ldctx, ldvalue.String(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(ctx, EvaluationRequest{
+		Key:             key,
+		Context:         ldctx,
+		CallsiteDefault: ldvalue.String(defaultVal),
+		Kind:            StringKind,
+	})
 	return detail.Value.StringValue(), detail, err
 }
@@ -15,6 +15,7 @@ import (
 	ld "github.com/launchdarkly/go-server-sdk/v7"
 
 	"github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
 )
 
 func TestObservable(t *testing.T) {
@@ -115,3 +116,44 @@ func TestObservable(t *testing.T) {
 		}
 	}
 }
+
+func TestNilContext(t *testing.T) {
+	var ctx context.Context // as if the caller had none to hand
+	fake := goldhooktest.NewFake().
+		Set("nil", goldhooktest.Serve(ldvalue.Bool(true), 0))
+	ldctx := ldcontext.New("nil")
+
+	shadow, err := goldhook.NewShadowEvaluator(ctx, fake, fake, func(context.Context, goldhook.Divergence) {}, 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	fallback, err := goldhook.NewFallbackEvaluator(ctx, goldhook.ClientSource("shadow", shadow))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := goldhook.NewEvaluator(ctx, fallback, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// neither the constructors' nil, nor that given to WithContext or to a Ctx
+	// method, may panic
+	if got, _ := hooked.BoolVariation("nil", ldctx, false); !got {
+		t.Errorf("expected true\n")
+	}
+	if got, _ := hooked.WithContext(ctx).BoolVariation("nil", ldctx, false); !got {
+		t.Errorf("expected true\n")
+	}
+	if got, _ := hooked.BoolVariationCtx(ctx, "nil", ldctx, false); !got {
+		t.Errorf("expected true\n")
+	}
+	if got, _ := shadow.BoolVariationCtx(ctx, "nil", ldctx, false); !got {
+		t.Errorf("expected true\n")
+	}
+	if err := shadow.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected: %v\n", err)
+	}
+	recorder.AssertEvaluated(t, "nil", 3)
+	recorder.AssertNoErrors(t)
+}
@@ -0,0 +1,113 @@
+package goldhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-server-sdk/v7/interfaces/flagstate"
+
+	v6 "github.com/nelz9999/goldhook/v6"
+)
+
+// Client is the v6 Client, plus the AllFlagsState of the v7 SDK, whose
+// flagstate package the v6 module doesn't depend upon
+type Client interface {
+	v6.Client
+	AllFlagsState(ldctx ldcontext.Context, options ...flagstate.Option) flagstate.AllFlags
+}
+
+// ObservedClient is a v6 ObservedClient which also observes the AllFlagsState
+// of a v7 LDClient.
+type ObservedClient struct {
+	*v6.ObservedClient
+	client Client
+}
+
+// NewObservedClient wraps the ObservedEvaluator (which must itself wrap a v7
+// Client) with the given observers of the other Client methods.
+func NewObservedClient(oe *v6.ObservedEvaluator, observers ...v6.ClientObserver) (*ObservedClient, error) {
+	oc, err := v6.NewObservedClient(oe, observers...)
+	if err != nil {
+		return nil, err
+	}
+	client, ok := oc.Client().(Client)
+	if !ok {
+		return nil, fmt.Errorf("evaluator must wrap a v7 Client; got %T", oc.Client())
+	}
+	return &ObservedClient{
+		ObservedClient: oc,
+		client:         client,
+	}, nil
+}
+
+func (oc *ObservedClient) WithContext(c context.Context) v6.Evaluator {
+	cp := *oc
+	cp.ObservedClient = oc.ObservedClient.WithContext(c).(*v6.ObservedClient)
+	return &cp
+}
+
+// WithInterceptors is as per v6.ObservedClient.WithInterceptors
+func (oc *ObservedClient) WithInterceptors(interceptors ...v6.Interceptor) (*ObservedClient, error) {
+	inner, err := oc.ObservedClient.WithInterceptors(interceptors...)
+	if err != nil {
+		return nil, err
+	}
+	cp := *oc
+	cp.ObservedClient = inner
+	return &cp, nil
+}
+
+// WithPanicHandler is as per v6.ObservedClient.WithPanicHandler
+func (oc *ObservedClient) WithPanicHandler(fn v6.PanicHandler) *ObservedClient {
+	cp := *oc
+	cp.ObservedClient = oc.ObservedClient.WithPanicHandler(fn)
+	return &cp
+}
+
+// WithQuarantine is as per v6.ObservedClient.WithQuarantine
+func (oc *ObservedClient) WithQuarantine(limit int, window, cooldown time.Duration) *ObservedClient {
+	cp := *oc
+	cp.ObservedClient = oc.ObservedClient.WithQuarantine(limit, window, cooldown)
+	return &cp
+}
+
+// WithRegistry is as per v6.ObservedClient.WithRegistry
+func (oc *ObservedClient) WithRegistry(r *v6.Registry) *ObservedClient {
+	cp := *oc
+	cp.ObservedClient = oc.ObservedClient.WithRegistry(r)
+	return &cp
+}
+
+// AllFlagsState conforms to the Client interface. Every flag in the returned
+// state is handed to ObserveAllFlags, and any flag whose result an
+// Interceptor changes (as Override would) is changed in the state returned,
+// too, unless it is changed to null (as the callsite default of KillSwitch
+// would be).
+func (oc *ObservedClient) AllFlagsState(ldctx ldcontext.Context, options ...flagstate.Option) flagstate.AllFlags {
+	start := time.Now()
+	state := oc.client.AllFlagsState(ldctx, options...)
+	elapsed := time.Since(start)
+
+	values := state.ToValuesMap()
+	flags := make(map[string]ldreason.EvaluationDetail, len(values))
+	for key := range values {
+		fs, _ := state.GetFlag(key)
+		flags[key] = ldreason.EvaluationDetail{Value: fs.Value, VariationIndex: fs.Variation, Reason: fs.Reason}
+	}
+
+	if changed := oc.ObserveAllFlags(ldctx, flags, elapsed); len(changed) > 0 {
+		builder := flagstate.NewAllFlagsBuilder(options...)
+		for key := range values {
+			fs, _ := state.GetFlag(key)
+			if detail, ok := changed[key]; ok {
+				fs.Value, fs.Variation, fs.Reason = detail.Value, detail.VariationIndex, detail.Reason
+			}
+			builder.AddFlag(key, fs)
+		}
+		state = builder.Build()
+	}
+	return state
+}
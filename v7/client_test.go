@@ -0,0 +1,242 @@
+package goldhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	ld "github.com/launchdarkly/go-server-sdk/v7"
+	"github.com/launchdarkly/go-server-sdk/v7/interfaces/flagstate"
+
+	v6 "github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+	"github.com/nelz9999/goldhook/v7"
+)
+
+// bootstrapClient serves a fixed AllFlagsState, which an offline client can't
+type bootstrapClient struct {
+	*ld.LDClient
+	state flagstate.AllFlags
+}
+
+func (bc bootstrapClient) AllFlagsState(ldcontext.Context, ...flagstate.Option) flagstate.AllFlags {
+	return bc.state
+}
+
+func TestObservedClient(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer client.Close()
+	bc := bootstrapClient{
+		LDClient: client,
+		state: flagstate.NewAllFlagsBuilder(flagstate.OptionWithReasons()).
+			AddFlag("banner-"+now, flagstate.FlagState{
+				Value:     ldvalue.String(now),
+				Variation: ldvalue.NewOptionalInt(1),
+				Reason:    ldreason.NewEvalReasonFallthrough(),
+			}).
+			AddFlag("checkout-"+now, flagstate.FlagState{
+				Value:     ldvalue.Bool(true),
+				Variation: ldvalue.NewOptionalInt(0),
+				Reason:    ldreason.NewEvalReasonTargetMatch(),
+			}).
+			Build(),
+	}
+
+	if _, err := goldhook.NewObservedClient(nil); err == nil {
+		t.Errorf("expected an error for a nil evaluator\n")
+	}
+	plain, err := v6.NewEvaluator(context.Background(), goldhooktest.NewFake())
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if _, err := goldhook.NewObservedClient(plain); err == nil {
+		t.Errorf("expected an error for an evaluator without a Client\n")
+	}
+
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := v6.NewEvaluator(context.Background(), bc, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	type ctxKey string
+	var states, identities int
+	tracked := []string{}
+	observed, err := goldhook.NewObservedClient(hooked.WithPanicHandler(func(*v6.ObserverPanic) {}),
+		v6.ClientObserver{
+			OnAllFlagsState: func(_ context.Context, _ ldcontext.Context, flags map[string]ldreason.EvaluationDetail, _ time.Duration) {
+				states += len(flags)
+			},
+			OnIdentify: func(ctx context.Context, ldctx ldcontext.Context, err error) {
+				if ctx.Value(ctxKey("now")) == now && ldctx.Key() == now && err == nil {
+					identities++
+				}
+			},
+			OnTrackEvent: func(_ context.Context, eventName string, _ ldcontext.Context, _ error) {
+				tracked = append(tracked, eventName)
+			},
+			OnTrackData: func(_ context.Context, eventName string, _ ldcontext.Context, data ldvalue.Value, _ error) {
+				tracked = append(tracked, eventName+":"+data.StringValue())
+			},
+			OnTrackMetric: func(_ context.Context, eventName string, _ ldcontext.Context, metricValue float64, _ ldvalue.Value, _ error) {
+				tracked = append(tracked, fmt.Sprintf("%s:%v", eventName, metricValue))
+			},
+		},
+		v6.ClientObserver{
+			OnTrackEvent: func(context.Context, string, ldcontext.Context, error) {
+				panic(now)
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var _ goldhook.Client = observed
+	var _ v6.Client = observed
+
+	ldctx := ldcontext.New(now)
+	state := observed.AllFlagsState(ldctx, flagstate.OptionWithReasons())
+	if !state.GetValue("checkout-" + now).BoolValue() {
+		t.Errorf("unexpected state: %v\n", state.ToValuesMap())
+	}
+	if states != 2 {
+		t.Errorf("expected %d flags; got %d\n", 2, states)
+	}
+
+	// each flag in the state shows up as an evaluation
+	recorder.AssertEvaluated(t, "banner-"+now, 1)
+	recorder.AssertEvaluated(t, "checkout-"+now, 1)
+	recorder.AssertReason(t, "checkout-"+now, ldreason.EvalReasonTargetMatch, 1)
+	ev := recorder.Of("banner-" + now)[0]
+	if ev.Detail.Value.StringValue() != now || ev.Detail.VariationIndex.IntValue() != 1 || !ev.CallsiteDefault.IsNull() {
+		t.Errorf("unexpected: %+v\n", ev)
+	}
+
+	// individual evaluations are observed as ever
+	observed.BoolVariation("checkout-"+now, ldctx, false)
+	recorder.AssertEvaluated(t, "checkout-"+now, 2)
+
+	ctx := context.WithValue(context.Background(), ctxKey("now"), now)
+	contextual := observed.WithContext(ctx).(*goldhook.ObservedClient)
+	if err := contextual.Identify(ldctx); err != nil {
+		t.Errorf("unexpected: %v\n", err)
+	}
+	if identities != 1 {
+		t.Errorf("expected %d; got %d\n", 1, identities)
+	}
+
+	observed.TrackEvent("viewed", ldctx)
+	observed.TrackData("clicked", ldctx, ldvalue.String(now))
+	observed.TrackMetric("spent", ldctx, 9.99, ldvalue.Null())
+	if expected := fmt.Sprintf("[viewed clicked:%s spent:9.99]", now); fmt.Sprint(tracked) != expected {
+		t.Errorf("expected %s; got %v\n", expected, tracked)
+	}
+}
+
+func TestObservedClientChain(t *testing.T) {
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer client.Close()
+	bc := bootstrapClient{
+		LDClient: client,
+		state: flagstate.NewAllFlagsBuilder().
+			AddFlag("banner", flagstate.FlagState{Value: ldvalue.String("served"), Variation: ldvalue.NewOptionalInt(0)}).
+			AddFlag("legacy", flagstate.FlagState{Value: ldvalue.Bool(true), Variation: ldvalue.NewOptionalInt(1)}).
+			Build(),
+	}
+
+	violations := []*v6.FlagViolation{}
+	registry, err := v6.NewRegistry(
+		func(_ context.Context, v *v6.FlagViolation) {
+			violations = append(violations, v)
+		},
+		v6.FlagDeclaration{Key: "legacy", Kind: v6.BoolKind, Default: ldvalue.Bool(false), Deprecated: "gone soon"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := v6.NewEvaluator(context.Background(), bc, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	var seen map[string]ldreason.EvaluationDetail
+	tracks := 0
+	observed, err := goldhook.NewObservedClient(hooked,
+		v6.ClientObserver{
+			OnAllFlagsState: func(_ context.Context, _ ldcontext.Context, flags map[string]ldreason.EvaluationDetail, _ time.Duration) {
+				seen = flags
+			},
+		},
+		v6.ClientObserver{
+			OnTrackEvent: func(context.Context, string, ldcontext.Context, error) {
+				tracks++
+				panic("track")
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// each of these keeps the Client methods of the ObservedClient
+	observed, err = observed.WithInterceptors(
+		v6.Override("banner", ldvalue.String("overridden")),
+		v6.KillSwitch("legacy"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	panics := []*v6.ObserverPanic{}
+	observed = observed.
+		WithRegistry(registry).
+		WithPanicHandler(func(p *v6.ObserverPanic) {
+			panics = append(panics, p)
+		}).
+		WithQuarantine(1, time.Minute, 0)
+
+	// the flags in the state go through the interceptors, and the registry
+	ldctx := ldcontext.New("chain")
+	state := observed.AllFlagsState(ldctx)
+	if v := state.GetValue("banner").StringValue(); v != "overridden" {
+		t.Errorf("state - expected %q; got %q\n", "overridden", v)
+	}
+	if v := seen["banner"].Value.StringValue(); v != "overridden" {
+		t.Errorf("observed state - expected %q; got %q\n", "overridden", v)
+	}
+	// a flag killed has only the null default to serve, so is left as it was
+	if !state.GetValue("legacy").BoolValue() {
+		t.Errorf("state - unexpected: %v\n", state.ToValuesMap())
+	}
+	if ev := recorder.Of("banner"); len(ev) != 1 || ev[0].InterceptedBy != "override:banner" {
+		t.Errorf("banner - unexpected: %+v\n", ev)
+	}
+	if ev := recorder.Of("legacy"); len(ev) != 1 || ev[0].InterceptedBy != "killswitch" {
+		t.Errorf("legacy - unexpected: %+v\n", ev)
+	}
+	if len(violations) != 1 || violations[0].Kind != v6.DeprecatedFlag {
+		t.Errorf("violations - unexpected: %v\n", violations)
+	}
+
+	// a panicking ClientObserver is quarantined like any other
+	observed.TrackEvent("first", ldctx)
+	observed.TrackEvent("second", ldctx)
+	if tracks != 1 {
+		t.Errorf("tracks - expected %d; got %d\n", 1, tracks)
+	}
+	if len(panics) != 1 || panics[0].Index != 1 || !panics[0].Quarantined {
+		t.Errorf("panics - unexpected: %+v\n", panics)
+	}
+}
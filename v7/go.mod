@@ -0,0 +1,38 @@
+module github.com/nelz9999/goldhook/v7
+
+go 1.21
+
+require (
+	github.com/launchdarkly/go-sdk-common/v3 v3.1.0
+	github.com/launchdarkly/go-server-sdk/v7 v7.5.0
+	github.com/nelz9999/goldhook v0.0.0-00010101000000-000000000000
+	github.com/nelz9999/goldhook/migrate v0.0.0-00010101000000-000000000000
+	github.com/nelz9999/goldhook/v6 v6.0.0-00010101000000-000000000000
+	gopkg.in/launchdarkly/go-sdk-common.v2 v2.4.0
+)
+
+require (
+	github.com/google/uuid v1.1.1 // indirect
+	github.com/gregjones/httpcache v0.0.0-20171119193500-2bcd89a1743f // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/launchdarkly/ccache v1.1.0 // indirect
+	github.com/launchdarkly/eventsource v1.6.2 // indirect
+	github.com/launchdarkly/go-jsonstream/v3 v3.0.0 // indirect
+	github.com/launchdarkly/go-sdk-events/v3 v3.3.0 // indirect
+	github.com/launchdarkly/go-semver v1.0.2 // indirect
+	github.com/launchdarkly/go-server-sdk-evaluation/v3 v3.0.0 // indirect
+	github.com/mailru/easyjson v0.7.6 // indirect
+	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
+	golang.org/x/exp v0.0.0-20220823124025-807a23277127 // indirect
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
+	gopkg.in/launchdarkly/go-jsonstream.v1 v1.0.1 // indirect
+	gopkg.in/launchdarkly/go-server-sdk.v5 v5.6.0 // indirect
+)
+
+// the bridge is developed alongside the modules it bridges, which are only
+// required at released versions once they are tagged
+replace (
+	github.com/nelz9999/goldhook => ../
+	github.com/nelz9999/goldhook/migrate => ../migrate
+	github.com/nelz9999/goldhook/v6 => ../v6
+)
@@ -0,0 +1,64 @@
+// Package goldhook bridges the observers of the goldhook modules and the
+// hooks of the LaunchDarkly Go SDK v7 (ldhooks), in both directions, so that
+// telemetry need not be rewritten when moving between SDK versions.
+package goldhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-server-sdk/v7/ldhooks"
+
+	v6 "github.com/nelz9999/goldhook/v6"
+)
+
+const startKey = "goldhook.start"
+
+// NewHook adapts a goldhook Observer into an ldhooks.Hook, to be registered
+// with a v7 client (via ld.Config.Hooks). The elapsed time handed to the
+// Observer is that between the Before and After stages of the evaluation.
+//
+// Hooks are not told of any error returned by the evaluation, so the Observer
+// is always handed a nil error; the reason in the detail still says whether
+// (and how) the evaluation erred.
+func NewHook(name string, o v6.Observer) ldhooks.Hook {
+	return &observerHook{
+		metadata: ldhooks.NewMetadata(name),
+		observer: o,
+	}
+}
+
+type observerHook struct {
+	ldhooks.Unimplemented
+	metadata ldhooks.Metadata
+	observer v6.Observer
+}
+
+func (oh *observerHook) Metadata() ldhooks.Metadata {
+	return oh.metadata
+}
+
+func (oh *observerHook) BeforeEvaluation(
+	_ context.Context,
+	_ ldhooks.EvaluationSeriesContext,
+	data ldhooks.EvaluationSeriesData,
+) (ldhooks.EvaluationSeriesData, error) {
+	return ldhooks.NewEvaluationSeriesBuilder(data).Set(startKey, time.Now()).Build(), nil
+}
+
+func (oh *observerHook) AfterEvaluation(
+	ctx context.Context,
+	sc ldhooks.EvaluationSeriesContext,
+	data ldhooks.EvaluationSeriesData,
+	detail ldreason.EvaluationDetail,
+) (ldhooks.EvaluationSeriesData, error) {
+	var elapsed time.Duration
+	if v, ok := data.Get(startKey); ok {
+		if start, ok := v.(time.Time); ok {
+			elapsed = time.Since(start)
+		}
+	}
+	oh.observer.Observe(ctx, sc.FlagKey(), sc.Context(), sc.DefaultValue(), elapsed, detail, nil)
+	return data, nil
+}
@@ -0,0 +1,48 @@
+package goldhook_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	ld "github.com/launchdarkly/go-server-sdk/v7"
+	"github.com/launchdarkly/go-server-sdk/v7/ldhooks"
+
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+	"github.com/nelz9999/goldhook/v7"
+)
+
+func TestNewHook(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	recorder := goldhooktest.NewRecordingObserver()
+	hook := goldhook.NewHook("recorder", recorder)
+	if hook.Metadata().Name() != "recorder" {
+		t.Errorf("expected %q; got %q\n", "recorder", hook.Metadata().Name())
+	}
+
+	client, err := ld.MakeCustomClient("", ld.Config{Offline: true, Hooks: []ldhooks.Hook{hook}}, 0)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	defer client.Close()
+
+	user := ldcontext.New(now)
+	client.BoolVariation("bool-"+now, user, true)
+	client.StringVariation("string-"+now, user, now)
+	client.StringVariation("string-"+now, user, now)
+
+	recorder.AssertEvaluated(t, "bool-"+now, 1)
+	recorder.AssertEvaluated(t, "string-"+now, 2)
+	recorder.AssertReason(t, "string-"+now, ldreason.EvalReasonError, 2)
+
+	ev := recorder.Of("bool-" + now)[0]
+	if ev.Context.Key() != now || !ev.CallsiteDefault.BoolValue() || !ev.Detail.Value.BoolValue() {
+		t.Errorf("unexpected: %+v\n", ev)
+	}
+	if ev.Elapsed < 0 || ev.Err != nil {
+		t.Errorf("unexpected: %v %v\n", ev.Elapsed, ev.Err)
+	}
+}
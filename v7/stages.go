@@ -0,0 +1,93 @@
+package goldhook
+
+import (
+	"context"
+	"log"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/ldhooks"
+
+	v5 "github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/migrate"
+	v6 "github.com/nelz9999/goldhook/v6"
+)
+
+const seriesKey = "goldhook.series"
+
+// HookStages adapts an ldhooks.Hook into a StagedObserver, so that hooks
+// written for the v7 SDK can run inside a v6 ObservedEvaluator. Any error the
+// hook returns is logged, as the v7 SDK would do.
+func HookStages(h ldhooks.Hook) v6.StagedObserver {
+	return hookStages{h}
+}
+
+// HookStagesV5 adapts an ldhooks.Hook into a StagedObserver, so that hooks
+// written for the v7 SDK can run inside a v5 (root module) ObservedEvaluator.
+// The hook sees each user as the equivalent context, as per
+// migrate.UserContext.
+func HookStagesV5(h ldhooks.Hook) v5.StagedObserver {
+	return hookStagesV5{hookStages{h}}
+}
+
+type hookStages struct {
+	hook ldhooks.Hook
+}
+
+func (hs hookStages) before(ctx context.Context, sc ldhooks.EvaluationSeriesContext) v6.SeriesData {
+	data, err := hs.hook.BeforeEvaluation(ctx, sc, ldhooks.EmptyEvaluationSeriesData())
+	hs.logError("BeforeEvaluation", sc, err)
+	return v6.SeriesData{seriesKey: data}
+}
+
+func (hs hookStages) after(ctx context.Context, sc ldhooks.EvaluationSeriesContext, sd v6.SeriesData, result v6.EvaluationResult) {
+	data, ok := sd[seriesKey].(ldhooks.EvaluationSeriesData)
+	if !ok {
+		data = ldhooks.EmptyEvaluationSeriesData()
+	}
+	_, err := hs.hook.AfterEvaluation(ctx, sc, data, result.Detail)
+	hs.logError("AfterEvaluation", sc, err)
+}
+
+func (hs hookStages) logError(stage string, sc ldhooks.EvaluationSeriesContext, err error) {
+	if err != nil {
+		log.Printf("goldhook: hook %q failed in %s of %q: %v", hs.hook.Metadata().Name(), stage, sc.FlagKey(), err)
+	}
+}
+
+func (hs hookStages) BeforeEvaluation(ctx context.Context, req v6.EvaluationRequest) v6.SeriesData {
+	return hs.before(ctx, seriesContext(req.Key, req.Context, req.CallsiteDefault, string(req.Kind)))
+}
+
+func (hs hookStages) AfterEvaluation(ctx context.Context, req v6.EvaluationRequest, data v6.SeriesData, result v6.EvaluationResult) {
+	hs.after(ctx, seriesContext(req.Key, req.Context, req.CallsiteDefault, string(req.Kind)), data, result)
+}
+
+type hookStagesV5 struct {
+	hookStages
+}
+
+func (hs hookStagesV5) BeforeEvaluation(ctx context.Context, req v5.EvaluationRequest) v5.SeriesData {
+	return v5.SeriesData(hs.before(ctx, userSeriesContext(req)))
+}
+
+func (hs hookStagesV5) AfterEvaluation(ctx context.Context, req v5.EvaluationRequest, data v5.SeriesData, result v5.EvaluationResult) {
+	hs.after(ctx, userSeriesContext(req), v6.SeriesData(data), v6.EvaluationResult{Detail: migrate.DetailV6(result.Detail)})
+}
+
+// methods names the SDK method for each kind of value, as hooks expect
+var methods = map[string]string{
+	string(v6.BoolKind):    "BoolVariation",
+	string(v6.Float64Kind): "Float64Variation",
+	string(v6.IntKind):     "IntVariation",
+	string(v6.JSONKind):    "JSONVariation",
+	string(v6.StringKind):  "StringVariation",
+}
+
+func seriesContext(key string, ldctx ldcontext.Context, callsiteDefault ldvalue.Value, kind string) ldhooks.EvaluationSeriesContext {
+	return ldhooks.NewEvaluationSeriesContext(key, ldctx, callsiteDefault, methods[kind])
+}
+
+func userSeriesContext(req v5.EvaluationRequest) ldhooks.EvaluationSeriesContext {
+	return seriesContext(req.Key, migrate.UserContext(req.User), migrate.ValueV6(req.CallsiteDefault), string(req.Kind))
+}
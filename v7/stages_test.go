@@ -0,0 +1,137 @@
+package goldhook_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-sdk-common/v3/ldcontext"
+	"github.com/launchdarkly/go-sdk-common/v3/ldreason"
+	"github.com/launchdarkly/go-sdk-common/v3/ldvalue"
+	"github.com/launchdarkly/go-server-sdk/v7/ldhooks"
+	ldreasonV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldreason"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/lduser"
+	ldvalueV2 "gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	v5 "github.com/nelz9999/goldhook"
+	v5test "github.com/nelz9999/goldhook/goldhooktest"
+	v6 "github.com/nelz9999/goldhook/v6"
+	"github.com/nelz9999/goldhook/v6/goldhooktest"
+	"github.com/nelz9999/goldhook/v7"
+)
+
+type recordingHook struct {
+	ldhooks.Unimplemented
+	fail    error
+	series  []ldhooks.EvaluationSeriesContext
+	details []ldreason.EvaluationDetail
+	data    []ldhooks.EvaluationSeriesData
+}
+
+func (rh *recordingHook) Metadata() ldhooks.Metadata {
+	return ldhooks.NewMetadata("recording")
+}
+
+func (rh *recordingHook) BeforeEvaluation(
+	_ context.Context,
+	sc ldhooks.EvaluationSeriesContext,
+	data ldhooks.EvaluationSeriesData,
+) (ldhooks.EvaluationSeriesData, error) {
+	rh.series = append(rh.series, sc)
+	return ldhooks.NewEvaluationSeriesBuilder(data).Set("key", sc.FlagKey()).Build(), rh.fail
+}
+
+func (rh *recordingHook) AfterEvaluation(
+	_ context.Context,
+	_ ldhooks.EvaluationSeriesContext,
+	data ldhooks.EvaluationSeriesData,
+	detail ldreason.EvaluationDetail,
+) (ldhooks.EvaluationSeriesData, error) {
+	rh.details = append(rh.details, detail)
+	rh.data = append(rh.data, data)
+	return data, rh.fail
+}
+
+func TestHookStages(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := goldhooktest.NewFake().
+		Set("bool-"+now, goldhooktest.Serve(ldvalue.Bool(true), 1)).
+		Set("int-"+now, goldhooktest.Serve(ldvalue.Int(7), 2))
+
+	hook := &recordingHook{}
+	hooked, err := v6.NewStagedEvaluator(context.Background(), fake, goldhook.HookStages(hook))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := ldcontext.New(now)
+	hooked.BoolVariation("bool-"+now, user, false)
+	hooked.IntVariation("int-"+now, user, 0)
+
+	if len(hook.series) != 2 || len(hook.details) != 2 {
+		t.Fatalf("expected %d; got %d before, %d after\n", 2, len(hook.series), len(hook.details))
+	}
+	methods := []string{"BoolVariation", "IntVariation"}
+	for i, sc := range hook.series {
+		if sc.Method() != methods[i] || sc.Context().Key() != now {
+			t.Errorf("%d - unexpected: %+v\n", i, sc)
+		}
+		// the data returned from BeforeEvaluation is handed to AfterEvaluation
+		if v, ok := hook.data[i].Get("key"); !ok || v != sc.FlagKey() {
+			t.Errorf("%d - expected %q; got %v\n", i, sc.FlagKey(), v)
+		}
+	}
+	if d := hook.details[1]; d.Value.IntValue() != 7 || d.VariationIndex.IntValue() != 2 {
+		t.Errorf("unexpected: %+v\n", d)
+	}
+
+	// errors from the hook don't disturb the evaluation
+	hook.fail = errors.New(now)
+	if v, err := hooked.BoolVariation("bool-"+now, user, false); err != nil || !v {
+		t.Errorf("expected %t; got %t\n", true, v)
+	}
+}
+
+func TestHookStagesV5(t *testing.T) {
+	now := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	fake := v5test.NewFake().
+		Set("string-"+now, v5test.Serve(ldvalueV2.String(now), 3).WithReason(ldreasonV2.NewEvalReasonFallthrough()))
+
+	hook := &recordingHook{}
+	hooked, err := v5.NewStagedEvaluator(context.Background(), fake, goldhook.HookStagesV5(hook))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	user := lduser.NewUserBuilder(now).
+		Email("someone@example.com").AsPrivateAttribute().
+		Custom("tier", ldvalueV2.String("gold")).
+		Build()
+	hooked.StringVariation("string-"+now, user, "default")
+
+	if len(hook.series) != 1 || len(hook.details) != 1 {
+		t.Fatalf("expected %d; got %d before, %d after\n", 1, len(hook.series), len(hook.details))
+	}
+	sc := hook.series[0]
+	if sc.Method() != "StringVariation" || sc.DefaultValue().StringValue() != "default" {
+		t.Errorf("unexpected: %+v\n", sc)
+	}
+	ldctx := sc.Context()
+	if ldctx.Kind() != ldcontext.DefaultKind || ldctx.Key() != now {
+		t.Errorf("unexpected context: %v\n", ldctx)
+	}
+	if v := ldctx.GetValue("tier"); v.StringValue() != "gold" {
+		t.Errorf("expected %q; got %v\n", "gold", v)
+	}
+	if ldctx.PrivateAttributeCount() != 1 || ldctx.GetValue("email").StringValue() != "someone@example.com" {
+		t.Errorf("unexpected private attributes: %v\n", ldctx)
+	}
+	d := hook.details[0]
+	if d.Value.StringValue() != now || d.VariationIndex.IntValue() != 3 || d.Reason.GetKind() != ldreason.EvalReasonFallthrough {
+		t.Errorf("unexpected: %+v\n", d)
+	}
+}
@@ -11,48 +11,200 @@ import (
 )
 
 type ObservedEvaluator struct {
-	client Evaluator
-	hooks  []Observer
-	ctx    context.Context
+	client       Evaluator
+	stages       []StagedObserver
+	interceptors []Interceptor
+	onPanic      PanicHandler
+	quarantines  []*quarantine
+	registry     *Registry
+	ctx          context.Context
 }
 
 func NewEvaluator(ctx context.Context, client Evaluator, subscribers ...Observer) (*ObservedEvaluator, error) {
+	stages := make([]StagedObserver, len(subscribers))
+	for i, h := range subscribers {
+		if h == nil {
+			return nil, fmt.Errorf("subscribers must not be nil")
+		}
+		stages[i] = AsStaged(h)
+	}
+	return NewStagedEvaluator(ctx, client, stages...)
+}
+
+func NewEventEvaluator(ctx context.Context, client Evaluator, observers ...EventObserver) (*ObservedEvaluator, error) {
+	stages := make([]StagedObserver, len(observers))
+	for i, eo := range observers {
+		if eo == nil {
+			return nil, fmt.Errorf("observers must not be nil")
+		}
+		stages[i] = StagedEvents(eo)
+	}
+	return NewStagedEvaluator(ctx, client, stages...)
+}
+
+func NewStagedEvaluator(ctx context.Context, client Evaluator, stages ...StagedObserver) (*ObservedEvaluator, error) {
 	if client == nil {
 		return nil, fmt.Errorf("client must not be nil")
 	}
-	// we don't check that there are 1+ subscribers vis Postel's Law
-	// but we do check for nil subscribers which could cause later panics
-	for _, h := range subscribers {
-		if h == nil {
-			return nil, fmt.Errorf("subscribers must not be nil")
+	// we don't check that there are 1+ stages vis Postel's Law
+	// but we do check for nil stages which could cause later panics
+	for _, s := range stages {
+		if s == nil {
+			return nil, fmt.Errorf("stages must not be nil")
 		}
 	}
 	return &ObservedEvaluator{
 		client: client,
-		hooks:  subscribers,
-		ctx:    ctx,
+		stages: stages,
+		ctx:    orBackground(ctx),
 	}, nil
 }
 
 func (oe *ObservedEvaluator) WithContext(c context.Context) Evaluator {
-	return &ObservedEvaluator{
-		client: oe.client,
-		hooks:  oe.hooks,
-		ctx:    c,
+	cp := *oe
+	cp.ctx = orBackground(c)
+	return &cp
+}
+
+// orBackground stands context.Background in for a nil ctx, which would
+// otherwise panic once an evaluation derives a context from it
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// WithInterceptors returns a copy of the ObservedEvaluator which runs every
+// evaluation through the given interceptors (in addition to any it already
+// had), outermost first, before it reaches the underlying client.
+func (oe *ObservedEvaluator) WithInterceptors(interceptors ...Interceptor) (*ObservedEvaluator, error) {
+	for _, ic := range interceptors {
+		if ic == nil {
+			return nil, fmt.Errorf("interceptors must not be nil")
+		}
+	}
+	cp := *oe
+	cp.interceptors = append(append([]Interceptor{}, oe.interceptors...), interceptors...)
+	return &cp, nil
+}
+
+// WithPanicHandler returns a copy of the ObservedEvaluator which reports any
+// panic recovered from its observers to the given handler, rather than to the
+// standard logger.
+func (oe *ObservedEvaluator) WithPanicHandler(fn PanicHandler) *ObservedEvaluator {
+	cp := *oe
+	cp.onPanic = fn
+	return &cp
+}
+
+// WithQuarantine returns a copy of the ObservedEvaluator which stops calling
+// any observer that panics limit times within window, for the cooldown that
+// follows (or for good, if cooldown is not positive).
+func (oe *ObservedEvaluator) WithQuarantine(limit int, window, cooldown time.Duration) *ObservedEvaluator {
+	cp := *oe
+	cp.quarantines = newQuarantines(len(oe.stages), limit, window, cooldown)
+	return &cp
+}
+
+// WithRegistry returns a copy of the ObservedEvaluator which checks every
+// evaluation against the declarations in the Registry, before it is made.
+func (oe *ObservedEvaluator) WithRegistry(r *Registry) *ObservedEvaluator {
+	cp := *oe
+	cp.registry = r
+	return &cp
+}
+
+func (oe *ObservedEvaluator) evaluate(req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+	return oe.evaluateWith(req, oe.variation)
+}
+
+// evaluateWith runs the request through the Registry, the stages and the
+// interceptors, with base in place of the client beneath them
+func (oe *ObservedEvaluator) evaluateWith(req EvaluationRequest, base EvaluateFunc) (ldreason.EvaluationDetail, error) {
+	ctx, ext := withExtensions(oe.ctx)
+	ctx = withPanicHandler(ctx, oe.onPanic)
+	if oe.registry != nil {
+		oe.check(ctx, req)
+	}
+	data := make([]SeriesData, len(oe.stages))
+	for i := range oe.stages {
+		data[i] = oe.before(ctx, i, req)
+	}
+	start := time.Now()
+	detail, by, err := intercept(ctx, req, oe.interceptors, base)
+	result := EvaluationResult{
+		Start:         start,
+		Elapsed:       time.Since(start),
+		Detail:        detail,
+		Err:           err,
+		InterceptedBy: by,
+		Extensions:    ext.snapshot(),
+	}
+	for i := range oe.stages {
+		oe.after(ctx, i, req, data[i], result)
+	}
+	return detail, err
+}
+
+func (oe *ObservedEvaluator) check(ctx context.Context, req EvaluationRequest) {
+	defer oe.recoverRegistry(req.Key)
+	oe.registry.check(ctx, req, time.Now())
+}
+
+func (oe *ObservedEvaluator) before(ctx context.Context, i int, req EvaluationRequest) SeriesData {
+	if oe.quarantined(i) {
+		return nil
 	}
+	defer oe.recoverStage(i, req.Key)
+	return oe.stages[i].BeforeEvaluation(ctx, req)
 }
 
-func (oe *ObservedEvaluator) notifyHooks(
-	key string,
-	user lduser.User,
-	callsiteDefault ldvalue.Value,
-	elapsed time.Duration,
-	detail ldreason.EvaluationDetail,
-	evalErr error,
-) {
-	for _, h := range oe.hooks {
-		h.Observe(oe.ctx, key, user, callsiteDefault, elapsed, detail, evalErr)
+func (oe *ObservedEvaluator) after(ctx context.Context, i int, req EvaluationRequest, data SeriesData, result EvaluationResult) {
+	if oe.quarantined(i) {
+		return
 	}
+	defer oe.recoverStage(i, req.Key)
+	oe.stages[i].AfterEvaluation(ctx, req, data, result)
+}
+
+// requestEvaluator is implemented by the Evaluators of this package which set
+// extensions on the evaluation, such as FallbackEvaluator, so that they are
+// handed the context of each evaluation (which carries the extensions) along
+// with the request itself
+type requestEvaluator interface {
+	evaluate(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error)
+}
+
+// variation hands the request to the client, which any other
+// ContextualEvaluator (such as a ShadowEvaluator, or another
+// ObservedEvaluator) sees bound to the context of the evaluation
+func (oe *ObservedEvaluator) variation(ctx context.Context, req EvaluationRequest) (ldreason.EvaluationDetail, error) {
+	if re, ok := oe.client.(requestEvaluator); ok {
+		return re.evaluate(ctx, req)
+	}
+	return variation(bind(ctx, oe.client), req)
+}
+
+// variation makes the request of the Evaluator, via the *VariationDetail
+// method for its kind
+func variation(e Evaluator, req EvaluationRequest) (detail ldreason.EvaluationDetail, err error) {
+	switch req.Kind {
+	case BoolKind:
+		_, detail, err = e.BoolVariationDetail(req.Key, req.User, req.CallsiteDefault.BoolValue())
+	case Float64Kind:
+		_, detail, err = e.Float64VariationDetail(req.Key, req.User, req.CallsiteDefault.Float64Value())
+	case IntKind:
+		_, detail, err = e.IntVariationDetail(req.Key, req.User, req.CallsiteDefault.IntValue())
+	case JSONKind:
+		_, detail, err = e.JSONVariationDetail(req.Key, req.User, req.CallsiteDefault)
+	case StringKind:
+		_, detail, err = e.StringVariationDetail(req.Key, req.User, req.CallsiteDefault.StringValue())
+	default:
+		err = fmt.Errorf("unknown value kind %q", req.Kind)
+		detail = ldreason.NewEvaluationDetailForError(ldreason.EvalErrorWrongType, req.CallsiteDefault)
+	}
+	return detail, err
 }
 
 func (oe *ObservedEvaluator) BoolVariation(key string, user lduser.User, defaultVal bool) (bool, error) {
@@ -61,9 +213,12 @@ func (oe *ObservedEvaluator) BoolVariation(key string, user lduser.User, default
 }
 
 func (oe *ObservedEvaluator) BoolVariationDetail(key string, user lduser.User, defaultVal bool) (bool, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.BoolVariationDetail(key, user, defaultVal)
-	oe.notifyHooks(key, user, ldvalue.Bool(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.Bool(defaultVal),
+		Kind:            BoolKind,
+	})
 	return detail.Value.BoolValue(), detail, err
 }
 
@@ -73,9 +228,12 @@ func (oe *ObservedEvaluator) Float64Variation(key string, user lduser.User, defa
 }
 
 func (oe *ObservedEvaluator) Float64VariationDetail(key string, user lduser.User, defaultVal float64) (float64, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.Float64VariationDetail(key, user, defaultVal)
-	oe.notifyHooks(key, user, ldvalue.Float64(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.Float64(defaultVal),
+		Kind:            Float64Kind,
+	})
 	return detail.Value.Float64Value(), detail, err
 }
 
@@ -85,9 +243,12 @@ func (oe *ObservedEvaluator) IntVariation(key string, user lduser.User, defaultV
 }
 
 func (oe *ObservedEvaluator) IntVariationDetail(key string, user lduser.User, defaultVal int) (int, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.IntVariationDetail(key, user, defaultVal)
-	oe.notifyHooks(key, user, ldvalue.Int(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.Int(defaultVal),
+		Kind:            IntKind,
+	})
 	return detail.Value.IntValue(), detail, err
 }
 
@@ -97,9 +258,12 @@ func (oe *ObservedEvaluator) JSONVariation(key string, user lduser.User, default
 }
 
 func (oe *ObservedEvaluator) JSONVariationDetail(key string, user lduser.User, defaultVal ldvalue.Value) (ldvalue.Value, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.JSONVariationDetail(key, user, defaultVal)
-	oe.notifyHooks(key, user, defaultVal, time.Since(start), detail, err)
+	detail, err := oe.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: defaultVal,
+		Kind:            JSONKind,
+	})
 	return detail.Value, detail, err
 }
 
@@ -109,8 +273,11 @@ func (oe *ObservedEvaluator) StringVariation(key string, user lduser.User, defau
 }
 
 func (oe *ObservedEvaluator) StringVariationDetail(key string, user lduser.User, defaultVal string) (string, ldreason.EvaluationDetail, error) {
-	start := time.Now()
-	_, detail, err := oe.client.StringVariationDetail(key, user, defaultVal)
-	oe.notifyHooks(key, user, ldvalue.String(defaultVal), time.Since(start), detail, err)
+	detail, err := oe.evaluate(EvaluationRequest{
+		Key:             key,
+		User:            user,
+		CallsiteDefault: ldvalue.String(defaultVal),
+		Kind:            StringKind,
+	})
 	return detail.Value.StringValue(), detail, err
 }
@@ -14,6 +14,7 @@ import (
 	ld "gopkg.in/launchdarkly/go-server-sdk.v5"
 
 	"github.com/nelz9999/goldhook"
+	"github.com/nelz9999/goldhook/goldhooktest"
 )
 
 func TestObservable(t *testing.T) {
@@ -114,3 +115,37 @@ func TestObservable(t *testing.T) {
 		}
 	}
 }
+
+func TestNilContext(t *testing.T) {
+	var ctx context.Context // as if the caller had none to hand
+	fake := goldhooktest.NewFake().
+		Set("nil", goldhooktest.Serve(ldvalue.Bool(true), 0))
+	user := lduser.NewUser("nil")
+
+	shadow, err := goldhook.NewShadowEvaluator(ctx, fake, fake, func(context.Context, goldhook.Divergence) {}, 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	fallback, err := goldhook.NewFallbackEvaluator(ctx, goldhook.ClientSource("shadow", shadow))
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	recorder := goldhooktest.NewRecordingObserver()
+	hooked, err := goldhook.NewEvaluator(ctx, fallback, recorder)
+	if err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+
+	// neither the constructors' nil nor that given to WithContext may panic
+	if got, _ := hooked.BoolVariation("nil", user, false); !got {
+		t.Errorf("expected true\n")
+	}
+	if got, _ := hooked.WithContext(ctx).BoolVariation("nil", user, false); !got {
+		t.Errorf("expected true\n")
+	}
+	if err := shadow.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected: %v\n", err)
+	}
+	recorder.AssertEvaluated(t, "nil", 2)
+	recorder.AssertNoErrors(t)
+}